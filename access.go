@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// AccessRule restricts an endpoint group by client address. Deny entries
+// win; when Allow is non-empty only matching clients get through. Entries
+// are CIDR prefixes or single addresses.
+type AccessRule struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+type compiledAccessRule struct {
+	allow, deny []netip.Prefix
+}
+
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid address %q", entry)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CIDR %q", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r compiledAccessRule) Permits(addr netip.Addr) bool {
+	if containsAddr(r.deny, addr) {
+		return false
+	}
+	return len(r.allow) == 0 || containsAddr(r.allow, addr)
+}
+
+var endpointGroups = []string{"admin", "telemetry", "grpc", "public"}
+
+func endpointGroup(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/admin/"):
+		return "admin"
+	case strings.HasPrefix(path, "/stats/"), strings.HasPrefix(path, "/debug/"), path == "/metrics", path == "/healthz/deep":
+		return "telemetry"
+	case strings.HasPrefix(path, grpcServicePath):
+		return "grpc"
+	}
+	return "public"
+}
+
+type accessControl struct {
+	rules map[string]compiledAccessRule
+}
+
+func newAccessControl(rules map[string]AccessRule) (*accessControl, error) {
+	ac := &accessControl{rules: make(map[string]compiledAccessRule)}
+	for group, rule := range rules {
+		if !slices.Contains(endpointGroups, group) {
+			return nil, fmt.Errorf("Unknown endpoint group %q", group)
+		}
+		allow, err := parsePrefixes(rule.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("Access rule for %s: %w", group, err)
+		}
+		deny, err := parsePrefixes(rule.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("Access rule for %s: %w", group, err)
+		}
+		ac.rules[group] = compiledAccessRule{allow: allow, deny: deny}
+	}
+	return ac, nil
+}
+
+// Wrap checks the connecting address, not forwarded headers, so the rules
+// hold even when nothing sits in front of the server.
+func (ac *accessControl) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, ok := ac.rules[endpointGroup(r.URL.Path)]
+		if ok {
+			addr, err := netip.ParseAddr(clientIp(r))
+			if err != nil || !rule.Permits(addr.Unmap()) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
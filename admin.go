@@ -0,0 +1,805 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type adminState struct {
+	mu sync.RWMutex
+	// pins holds the version each pinned channel serves, persisted with
+	// the history so promotions and rollbacks survive a restart.
+	pins map[string]string
+	// blocked holds the versions yanked at runtime, persisted to
+	// blocklistPath, and configBlocked those listed in blockedVersions,
+	// which cannot be unblocked at runtime. Both are keyed by
+	// "group:artifact:version".
+	blocked       map[string]bool
+	configBlocked map[string]bool
+	blocklistPath string
+	// history lists the versions each channel advertised, oldest first,
+	// persisted to historyPath so rollbacks still work after a restart.
+	history     map[string][]string
+	historyPath string
+}
+
+// releaseState is what historyPath holds.
+type releaseState struct {
+	Pins    map[string]string   `json:"pins"`
+	History map[string][]string `json:"history"`
+}
+
+const maxReleaseHistory = 20
+
+var (
+	errNotBlocked      = errors.New("Version is not yanked")
+	errBlockedByConfig = errors.New("Version is blocked by the config")
+	errNoRollback      = errors.New("No previous version to roll back to")
+)
+
+func newAdminState(blocklistPath, historyPath string) *adminState {
+	return &adminState{
+		pins:          make(map[string]string),
+		blocked:       make(map[string]bool),
+		configBlocked: make(map[string]bool),
+		blocklistPath: blocklistPath,
+		history:       make(map[string][]string),
+		historyPath:   historyPath,
+	}
+}
+
+// LoadHistory loads the pins and release history.
+func (s *adminState) LoadHistory() error {
+	if s.historyPath == "" {
+		return nil
+	}
+	state := releaseState{Pins: make(map[string]string), History: make(map[string][]string)}
+	if err := readJsonFile(s.historyPath, &state); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pins, s.history = state.Pins, state.History
+	return nil
+}
+
+func (s *adminState) persistHistory() error {
+	if s.historyPath == "" {
+		return nil
+	}
+	return writeJsonFile(s.historyPath, releaseState{Pins: s.pins, History: s.history})
+}
+
+func (s *adminState) LoadBlocklist() error {
+	if s.blocklistPath == "" {
+		return nil
+	}
+	var keys []string
+	if err := readJsonFile(s.blocklistPath, &keys); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		s.blocked[key] = true
+	}
+	return nil
+}
+
+// BlockByConfig replaces the versions blocked by the config with those of
+// cfg's blockedVersions.
+func (s *adminState) BlockByConfig(cfg Config) {
+	blocked := make(map[string]bool)
+	for product, versions := range cfg.BlockedVersions {
+		artifact := cfg.Artifacts[product]
+		for _, version := range versions {
+			blocked[artifact.Group+":"+artifact.Artifact+":"+version] = true
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configBlocked = blocked
+}
+
+func (s *adminState) persistBlocklist() error {
+	if s.blocklistPath == "" {
+		return nil
+	}
+	keys := make([]string, 0, len(s.blocked))
+	for key := range s.blocked {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return writeJsonFile(s.blocklistPath, keys)
+}
+
+func (s *adminState) Pin(branch string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	version, ok := s.pins[branch]
+	return version, ok
+}
+
+// SetPin pins a channel to version, or unpins it if version is empty. The
+// pin applies even if it cannot be persisted.
+func (s *adminState) SetPin(branch, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == "" {
+		delete(s.pins, branch)
+	} else {
+		s.pins[branch] = version
+	}
+	return s.persistHistory()
+}
+
+func (s *adminState) IsBlocked(coordinates, version string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isBlocked(coordinates + ":" + version)
+}
+
+func (s *adminState) isBlocked(key string) bool {
+	return s.blocked[key] || s.configBlocked[key]
+}
+
+// Block yanks a version. It stays blocked in memory even if the blocklist
+// cannot be written.
+func (s *adminState) Block(coordinates, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blocked[coordinates+":"+version] = true
+	return s.persistBlocklist()
+}
+
+func (s *adminState) Unblock(coordinates, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := coordinates + ":" + version
+	if s.configBlocked[key] {
+		return errBlockedByConfig
+	}
+	if !s.blocked[key] {
+		return errNotBlocked
+	}
+	delete(s.blocked, key)
+	return s.persistBlocklist()
+}
+
+func (s *adminState) RecordRelease(channel, previous, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.history[channel]
+	if len(history) == 0 && previous != "" {
+		history = append(history, previous)
+	}
+	if len(history) > 0 && history[len(history)-1] == version {
+		return nil
+	}
+	history = append(history, version)
+	s.history[channel] = history[max(0, len(history)-maxReleaseHistory):]
+	return s.persistHistory()
+}
+
+// PreviousRelease returns the newest version the channel advertised other
+// than version, or "" if there is none.
+func (s *adminState) PreviousRelease(channel, version string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	history := s.history[channel]
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i] != version {
+			return history[i]
+		}
+	}
+	return ""
+}
+
+// Rollback pins a channel to the newest version it advertised before current
+// that is not blocked. The versions rolled back from are dropped from the
+// history, so repeated rollbacks keep going back and never return to them.
+// The pin applies even if it cannot be written.
+func (s *adminState) Rollback(channel, coordinates, current string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.history[channel]
+	dropped := map[string]bool{current: true}
+	for i := len(history) - 1; i >= 0; i-- {
+		version := history[i]
+		if dropped[version] || s.isBlocked(coordinates+":"+version) {
+			dropped[version] = true
+			continue
+		}
+		s.history[channel] = slices.DeleteFunc(slices.Clone(history[:i+1]), func(v string) bool { return dropped[v] })
+		s.pins[channel] = version
+		return version, s.persistHistory()
+	}
+	return "", errNoRollback
+}
+
+func (s *adminState) Blocked() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := make([]string, 0, len(s.blocked)+len(s.configBlocked))
+	for version := range s.blocked {
+		versions = append(versions, version)
+	}
+	for version := range s.configBlocked {
+		if !s.blocked[version] {
+			versions = append(versions, version)
+		}
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+var admin = newAdminState("", "")
+
+func flushCaches() {
+	manifestCache.Clear()
+	negativeCache.Clear()
+	changelogMemo.Clear()
+	changelogVersions.Clear()
+	versionManifests.Clear()
+	feeds.Clear()
+}
+
+// flushProduct drops what is cached for the channels of a product, so a
+// newly blocked or unblocked version takes effect on the next request.
+func flushProduct(product string) {
+	for _, ch := range allChannels() {
+		if ch.Product == product {
+			manifestCache.Delete(ch.Key())
+		}
+	}
+	negativeCache.Clear()
+	versionManifests.Clear()
+	feeds.Clear()
+}
+
+type adminChannelStatus struct {
+	Version string   `json:"version,omitempty"`
+	Pinned  string   `json:"pinned,omitempty"`
+	Rollout *rollout `json:"rollout,omitempty"`
+	// Slots shows the versions in a channel's blue/green slots, if any.
+	Slots    *slotSummary `json:"slots,omitempty"`
+	Cached   bool         `json:"cached"`
+	Requests int64        `json:"requests"`
+	// DownloadSize is the total size of the jar and libraries in bytes,
+	// once measured for a new release.
+	DownloadSize int64 `json:"downloadSize,omitempty"`
+}
+
+type adminCacheStatus struct {
+	Manifests int `json:"manifests"`
+	Libraries int `json:"libraries"`
+	Negative  int `json:"negative"`
+}
+
+type adminError struct {
+	Time    time.Time `json:"time"`
+	Channel string    `json:"channel"`
+	Message string    `json:"message"`
+}
+
+type adminStatus struct {
+	Ready            bool                          `json:"ready"`
+	Channels         map[string]adminChannelStatus `json:"channels"`
+	Blocked          []string                      `json:"blocked"`
+	UpstreamRequests int64                         `json:"upstreamRequests"`
+	Caches           adminCacheStatus              `json:"caches"`
+	RecentErrors     []adminError                  `json:"recentErrors"`
+	Unavailable      map[string]string             `json:"unavailable,omitempty"`
+}
+
+const maxRecentErrors = 20
+
+type errorLog struct {
+	mu     sync.Mutex
+	errors []adminError
+}
+
+func (l *errorLog) Add(e adminError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, e)
+	if len(l.errors) > maxRecentErrors {
+		l.errors = l.errors[len(l.errors)-maxRecentErrors:]
+	}
+}
+
+func (l *errorLog) List() []adminError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]adminError(nil), l.errors...)
+}
+
+var recentErrors = &errorLog{}
+
+func subscribeErrorLog(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type == EventResolutionFailed {
+			recentErrors.Add(adminError{Time: e.Time, Channel: e.Channel, Message: e.Err.Error()})
+		}
+	})
+}
+
+// writePersistFailure answers 500 for a change that applies in memory but
+// could not be written, so it will not survive a restart.
+func writePersistFailure(w http.ResponseWriter, what string, err error) {
+	log.Printf("Error: failed to write %s: %v", what, err)
+	http.Error(w, "Failed to write "+what+", the change is lost on restart", http.StatusInternalServerError)
+}
+
+func subscribeReleaseHistory(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type == EventReleaseDetected {
+			if err := admin.RecordRelease(e.Channel, e.PreviousVersion, e.Version); err != nil {
+				log.Printf("Warning: failed to write release history: %v", err)
+			}
+		}
+	})
+}
+
+func buildAdminStatus() adminStatus {
+	status := adminStatus{
+		Ready:            ready.Load(),
+		Channels:         make(map[string]adminChannelStatus),
+		Blocked:          admin.Blocked(),
+		UpstreamRequests: upstreamRequests.Value(),
+		Caches: adminCacheStatus{
+			Manifests: manifestCache.Len(),
+			Libraries: librariesCache.Len(),
+			Negative:  negativeCache.Len(),
+		},
+		RecentErrors: recentErrors.List(),
+		Unavailable:  linkRot.Unavailable(),
+	}
+	for _, ch := range allChannels() {
+		key := ch.Key()
+		channelStatus := adminChannelStatus{Requests: channelRequests(key)}
+		if resp, ok := lastServed.Get(key); ok {
+			channelStatus.Version = resp.Version
+			channelStatus.DownloadSize, _ = knownDownloadSize(ch.Group+":"+ch.Artifact, resp.Version)
+		}
+		channelStatus.Pinned, _ = admin.Pin(key)
+		if ro, ok := rollouts.Get(key); ok {
+			channelStatus.Rollout = &ro
+		}
+		channelStatus.Slots = releaseSlots.Summary(key)
+		_, channelStatus.Cached = manifestCache.Get(key)
+		status.Channels[key] = channelStatus
+	}
+	return status
+}
+
+type adminRequest struct {
+	Channel string `json:"channel,omitempty"`
+	// Branch is accepted from clients predating channels, see legacyChannelKey.
+	Branch     string          `json:"branch,omitempty"`
+	Product    string          `json:"product,omitempty"`
+	Version    string          `json:"version,omitempty"`
+	Artifact   *ArtifactConfig `json:"artifact,omitempty"`
+	File       string          `json:"file,omitempty"`
+	TtlSeconds int             `json:"ttlSeconds,omitempty"`
+	Id         string          `json:"id,omitempty"`
+	BaseUrl    string          `json:"baseUrl,omitempty"`
+	Percentage int             `json:"percentage,omitempty"`
+	Mod        string          `json:"mod,omitempty"`
+	// Dependencies registers what Mod depends on, for "watch".
+	Dependencies *ModWatch `json:"dependencies,omitempty"`
+	// Client describes the launcher to simulate, for "simulate".
+	Client *simulatedClient `json:"client,omitempty"`
+}
+
+// adminCaller is who made an admin request: the name registrations are
+// recorded under and the role that decides what they may do.
+type adminCaller struct {
+	Name string
+	Role string
+}
+
+// authenticateAdmin returns the caller. The shared API token grants full
+// access, per-person tokens carry their configured name and role and
+// browser sessions the OIDC subject and the role mapped at login.
+func authenticateAdmin(r *http.Request) (adminCaller, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if config().AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(config().AdminToken)) == 1 {
+			return adminCaller{Name: "adminToken", Role: roleAdmin}, true
+		}
+		for _, t := range config().AdminTokens {
+			if t.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+				return adminCaller{Name: t.Name, Role: t.Role}, true
+			}
+		}
+		return adminCaller{}, false
+	}
+	if oidc != nil {
+		if session, ok := oidc.Session(r); ok {
+			return adminCaller{Name: "oidc:" + session.Subject, Role: session.Role}, true
+		}
+	}
+	return adminCaller{}, false
+}
+
+func writeAdminJson(w http.ResponseWriter, v any) {
+	writeJson(w, v)
+}
+
+func adminHandler(w http.ResponseWriter, r *http.Request) {
+	operation := strings.TrimPrefix(r.URL.Path, "/admin/")
+	if oidc != nil {
+		switch operation {
+		case "login":
+			oidc.Login(w, r)
+			return
+		case "callback":
+			oidc.Callback(w, r)
+			return
+		case "logout":
+			oidc.Logout(w, r)
+			return
+		}
+	}
+	caller, ok := authenticateAdmin(r)
+	role := caller.Role
+	if !ok {
+		if operation == "" && oidc != nil {
+			http.Redirect(w, r, "/admin/login", http.StatusFound)
+			return
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	op, ok := adminOperations[operation]
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != op.method {
+		w.Header().Set("Allow", op.method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mirror != nil && op.method != http.MethodGet {
+		http.Error(w, "Read-only mirror", http.StatusForbidden)
+		return
+	}
+	if !roleHasScope(role, op.scope) {
+		http.Error(w, "Forbidden: requires "+op.scope, http.StatusForbidden)
+		return
+	}
+	switch operation {
+	case "":
+		adminDashboard(w, role)
+		return
+	case "status":
+		writeAdminJson(w, buildAdminStatus())
+		return
+	case "jobs":
+		writeAdminJson(w, jobs.Status())
+		return
+	case "deliveries":
+		writeAdminJson(w, notifications.Status())
+		return
+	case "mirror":
+		if mirror == nil {
+			http.Error(w, "Not a mirror", http.StatusNotFound)
+			return
+		}
+		writeAdminJson(w, mirror.Status())
+		return
+	case "whoami":
+		writeAdminJson(w, map[string]any{"role": role, "scopes": roleScopes[role]})
+		return
+	case "mods":
+		writeAdminJson(w, modWatches.List())
+		return
+	case "import":
+		importBundle(w, r)
+		return
+	case "verify":
+		if !jobs.Submit("verify", sweepReleases) {
+			http.Error(w, "Job queue full", http.StatusServiceUnavailable)
+			return
+		}
+		writeAdminJson(w, jobs.Status())
+		return
+	}
+	var req adminRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	req.Channel = legacyChannelKey(req.Channel, req.Branch)
+	if req.Product == "" && (operation == "yank" || operation == "unyank") {
+		req.Product = legacyProduct
+	}
+	switch operation {
+	case "watch":
+		if req.Dependencies == nil {
+			http.Error(w, "Missing dependencies", http.StatusBadRequest)
+			return
+		}
+		if err := validateModWatch(req.Mod, *req.Dependencies, roleHasScope(role, scopeSystemWrite)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		watch := *req.Dependencies
+		watch.Owner = caller.Name
+		switch err := modWatches.Watch(req.Mod, watch, roleHasScope(role, scopeReleasesWrite)); {
+		case errors.Is(err, errModOwned):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			writePersistFailure(w, "mod registrations", err)
+			return
+		}
+		writeAdminJson(w, modWatches.List())
+		return
+	case "unwatch":
+		switch err := modWatches.Unwatch(req.Mod, caller.Name, roleHasScope(role, scopeReleasesWrite)); {
+		case errors.Is(err, errModNotWatched):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, errModOwned):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case err != nil:
+			writePersistFailure(w, "mod registrations", err)
+			return
+		}
+		writeAdminJson(w, modWatches.List())
+		return
+	case "flush":
+		if req.Channel == "" {
+			flushCaches()
+			break
+		}
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		manifestCache.Delete(ch.Key())
+		negativeCache.Clear()
+		versionManifests.Clear()
+	case "promote":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		if _, slotted := releaseSlots.Current(ch.Key()); slotted {
+			http.Error(w, errChannelSlotted.Error(), http.StatusConflict)
+			return
+		}
+		if req.Version != "" && admin.IsBlocked(ch.Group+":"+ch.Artifact, req.Version) {
+			http.Error(w, "Version is yanked", http.StatusConflict)
+			return
+		}
+		err = admin.SetPin(ch.Key(), req.Version)
+		manifestCache.Delete(ch.Key())
+		if err != nil {
+			writePersistFailure(w, "pins", err)
+			return
+		}
+	case "rollback":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		if _, slotted := releaseSlots.Current(ch.Key()); slotted {
+			http.Error(w, errChannelSlotted.Error(), http.StatusConflict)
+			return
+		}
+		current, ok := lastServed.Get(ch.Key())
+		if !ok {
+			http.Error(w, "Channel has not been served yet", http.StatusConflict)
+			return
+		}
+		_, err = admin.Rollback(ch.Key(), ch.Group+":"+ch.Artifact, current.Version)
+		if errors.Is(err, errNoRollback) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		manifestCache.Delete(ch.Key())
+		if err != nil {
+			writePersistFailure(w, "pins", err)
+			return
+		}
+	case "rollout":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		if req.Version == "" || req.Percentage < 0 || req.Percentage > 100 {
+			http.Error(w, "Missing version or percentage outside 0-100", http.StatusBadRequest)
+			return
+		}
+		if err := startRollout(ch, req.Version, req.Percentage); errors.Is(err, errNoRolloutBaseline) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			writePersistFailure(w, "rollouts", err)
+			return
+		}
+	case "prepare":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		if req.Version == "" {
+			http.Error(w, "Missing version", http.StatusBadRequest)
+			return
+		}
+		if admin.IsBlocked(ch.Group+":"+ch.Artifact, req.Version) {
+			http.Error(w, "Version is yanked", http.StatusConflict)
+			return
+		}
+		next, err := releaseManifest(ch, req.Version)
+		if err != nil {
+			writeFailure(w, "Failed to resolve release for slot", err)
+			return
+		}
+		current, err := resolveChannel(ch)
+		if err != nil {
+			writeFailure(w, "Failed to resolve current release for slot", err)
+			return
+		}
+		err = releaseSlots.Prepare(ch.Key(), current, next)
+		manifestCache.Delete(ch.Key())
+		if err != nil {
+			writePersistFailure(w, "manifest slots", err)
+			return
+		}
+	case "switch":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		resp, err := releaseSlots.Switch(ch.Key())
+		if errors.Is(err, errNoNextSlot) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		storeResolved(ch, resp)
+		if err != nil {
+			writePersistFailure(w, "manifest slots", err)
+			return
+		}
+	case "unslot":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		err = releaseSlots.Clear(ch.Key())
+		if errors.Is(err, errNoSlots) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		manifestCache.Delete(ch.Key())
+		if err != nil {
+			writePersistFailure(w, "manifest slots", err)
+			return
+		}
+	case "yank":
+		artifact, err := artifacts.Lookup(req.Product)
+		if errors.Is(err, errArtifactNotRegistered) || req.Version == "" {
+			http.Error(w, "Missing or unknown product or version", http.StatusBadRequest)
+			return
+		}
+		err = admin.Block(artifact.Group+":"+artifact.Artifact, req.Version)
+		flushProduct(req.Product)
+		for _, ch := range allChannels() {
+			if resp, ok := lastServed.Get(ch.Key()); ok && ch.Product == req.Product && resp.Version == req.Version {
+				events.Publish(Event{Type: EventReleaseYanked, Channel: ch.Key(), Version: req.Version})
+			}
+		}
+		if err != nil {
+			writePersistFailure(w, "blocklist", err)
+			return
+		}
+	case "unyank":
+		artifact, err := artifacts.Lookup(req.Product)
+		if errors.Is(err, errArtifactNotRegistered) || req.Version == "" {
+			http.Error(w, "Missing or unknown product or version", http.StatusBadRequest)
+			return
+		}
+		switch err := admin.Unblock(artifact.Group+":"+artifact.Artifact, req.Version); {
+		case errors.Is(err, errNotBlocked):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, errBlockedByConfig):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			flushProduct(req.Product)
+			writePersistFailure(w, "blocklist", err)
+			return
+		}
+		flushProduct(req.Product)
+	case "sign":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		if req.File == "" {
+			req.File = "latest.json"
+		}
+		ttl := time.Duration(req.TtlSeconds) * time.Second
+		if ttl <= 0 || ttl > maxSignedUrlTTL {
+			http.Error(w, "ttlSeconds must be between 1 and "+strconv.Itoa(int(maxSignedUrlTTL.Seconds())), http.StatusBadRequest)
+			return
+		}
+		signed, expires := signUrl("/"+ch.Key()+"/"+strings.TrimPrefix(req.File, "/"), ttl)
+		writeAdminJson(w, map[string]any{"url": signed, "expires": expires})
+		return
+	case "simulate":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		if req.Client == nil {
+			req.Client = &simulatedClient{}
+		}
+		writeAdminJson(w, simulateClient(ch, *req.Client))
+		return
+	case "redeliver":
+		if err := notifications.Redeliver(req.Id); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeAdminJson(w, notifications.Status())
+		return
+	case "bundle":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		manifest, err := releaseManifest(ch, req.Version)
+		if err != nil {
+			writeFailure(w, "Failed to resolve release for bundle", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ch.Product+"-"+ch.Branch+"-"+manifest.Version+".zip"))
+		if err := writeBundle(w, ch, manifest, req.BaseUrl, manifestSigningKey); err != nil {
+			// Headers are already sent, so the truncated archive is the
+			// only signal the client gets besides the log.
+			log.Printf("Warning: failed to write bundle for %s %s: %v", ch.Key(), manifest.Version, err)
+		}
+		return
+	case "artifacts":
+		if req.Product == "" || req.Artifact == nil || req.Artifact.Group == "" || req.Artifact.Artifact == "" {
+			http.Error(w, "Missing product or artifact coordinates", http.StatusBadRequest)
+			return
+		}
+		if err := validatePlatformRules(req.Artifact.Platforms); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		artifacts.Register(req.Product, *req.Artifact)
+		flushCaches()
+	}
+	writeAdminJson(w, buildAdminStatus())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReleaseHistorySurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	s := newAdminState("", path)
+	for _, release := range [][2]string{{"1.0.0", "1.1.0"}, {"1.1.0", "1.2.0"}} {
+		if err := s.RecordRelease("selene-client/stable", release[0], release[1]); err != nil {
+			t.Fatalf("RecordRelease: %v", err)
+		}
+	}
+
+	restarted := newAdminState("", path)
+	if err := restarted.LoadHistory(); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	version, err := restarted.Rollback("selene-client/stable", "world.selene:selene-client", "1.2.0")
+	if err != nil || version != "1.1.0" {
+		t.Fatalf("Rollback = %q, %v, want 1.1.0", version, err)
+	}
+
+	restarted = newAdminState("", path)
+	if err := restarted.LoadHistory(); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	if version, err := restarted.Rollback("selene-client/stable", "world.selene:selene-client", "1.1.0"); err != nil || version != "1.0.0" {
+		t.Errorf("second Rollback = %q, %v, want 1.0.0", version, err)
+	}
+	if _, err := restarted.Rollback("selene-client/stable", "world.selene:selene-client", "1.0.0"); err != errNoRollback {
+		t.Errorf("Rollback past the oldest version = %v, want errNoRollback", err)
+	}
+}
+
+func TestPinsSurviveRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	s := newAdminState("", path)
+	if err := s.SetPin("selene-client/stable", "1.1.0"); err != nil {
+		t.Fatalf("SetPin: %v", err)
+	}
+	if err := s.RecordRelease("selene-client/beta", "1.1.0", "1.2.0"); err != nil {
+		t.Fatalf("RecordRelease: %v", err)
+	}
+	if _, err := s.Rollback("selene-client/beta", "world.selene:selene-client", "1.2.0"); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+
+	restarted := newAdminState("", path)
+	if err := restarted.LoadHistory(); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	for channel, want := range map[string]string{"selene-client/stable": "1.1.0", "selene-client/beta": "1.1.0"} {
+		if version, ok := restarted.Pin(channel); !ok || version != want {
+			t.Errorf("Pin(%s) = %q, %v, want %s", channel, version, ok, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const adminUsage = `usage: selene-update-server admin [-server url] [-token token] <command>
+
+commands:
+  whoami                                show the role and scopes of the token
+  status                                show channel versions, pins and blocked versions
+  jobs                                  show the background job queue
+  deliveries                            show notifier delivery status and dead letters
+  flush [channel]                       invalidate cached manifests, of all channels or one
+  simulate <channel> [clientId=id] [os=os] [arch=arch] [capabilities=a,b] [compact] [legacy]
+                                        show the exact manifest a client would get and why
+  promote <channel> <version>           pin a channel (e.g. selene-client/stable) to a version ("" to unpin)
+  rollback <channel>                    pin a channel to the version it advertised before the current one
+  yank [product] <version>              block a version of a product (default selene-client) from being advertised
+  unyank <product> <version>            advertise a yanked version again
+  rollout <channel> <version> <percent> roll a version out to a share of clients
+  prepare <channel> <version>           prepare a version in the channel's next slot
+  switch <channel>                      swap the channel's current and next slots
+  unslot <channel>                      drop the channel's slots and resolve releases again
+  verify                                re-check every file and checksum of all published versions
+  redeliver <id>                        retry delivering a dead-lettered notification
+  bundle <channel> <version> <out.zip> [base-url]
+                                        package a release ("latest" for the current one) for offline installs
+  import <bundle.zip>                   import an offline bundle into a filesystem backed server
+  sign <channel> <ttl> [file]           issue a signed URL for a private channel (e.g. 24h)
+  mods                                  list mods registered for dependency change warnings
+  watch <mod> <product> <webhook-url|notifier> <library>...
+                                        warn a webhook or configured notifier when a pending release changes these libraries
+  unwatch <mod>                         remove a mod's registration`
+
+func runAdminCli(args []string) error {
+	fs := flag.NewFlagSet("admin", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8080", "base URL of the running update server")
+	token := fs.String("token", os.Getenv("SELENE_ADMIN_TOKEN"), "admin API token (defaults to $SELENE_ADMIN_TOKEN)")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), adminUsage) }
+	fs.Parse(args)
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("Missing admin command")
+	}
+	var method, operation, output, upload string
+	var body any
+	switch {
+	case args[0] == "status" && len(args) == 1:
+		method, operation = http.MethodGet, "status"
+	case args[0] == "whoami" && len(args) == 1:
+		method, operation = http.MethodGet, "whoami"
+	case args[0] == "jobs" && len(args) == 1:
+		method, operation = http.MethodGet, "jobs"
+	case args[0] == "deliveries" && len(args) == 1:
+		method, operation = http.MethodGet, "deliveries"
+	case args[0] == "redeliver" && len(args) == 2:
+		method, operation = http.MethodPost, "redeliver"
+		body = adminRequest{Id: args[1]}
+	case args[0] == "mods" && len(args) == 1:
+		method, operation = http.MethodGet, "mods"
+	case args[0] == "watch" && len(args) >= 5:
+		method, operation = http.MethodPost, "watch"
+		notifier := NotifierConfig{Name: args[3]}
+		if strings.Contains(args[3], "://") {
+			notifier = NotifierConfig{Type: "webhook", Url: args[3]}
+		}
+		body = adminRequest{Mod: args[1], Dependencies: &ModWatch{
+			Product:   args[2],
+			Notifier:  notifier,
+			Libraries: args[4:],
+		}}
+	case args[0] == "unwatch" && len(args) == 2:
+		method, operation = http.MethodPost, "unwatch"
+		body = adminRequest{Mod: args[1]}
+	case args[0] == "simulate" && len(args) >= 2:
+		client := &simulatedClient{}
+		for _, arg := range args[2:] {
+			key, value, _ := strings.Cut(arg, "=")
+			switch key {
+			case "clientId":
+				client.ClientId = value
+			case "os":
+				client.Os = value
+			case "arch":
+				client.Arch = value
+			case "capabilities":
+				client.Capabilities = strings.Split(value, ",")
+			case "compact":
+				client.Compact = true
+			case "legacy":
+				client.Legacy = true
+			default:
+				return fmt.Errorf("Invalid client attribute %q", arg)
+			}
+		}
+		method, operation = http.MethodPost, "simulate"
+		body = adminRequest{Channel: args[1], Client: client}
+	case args[0] == "verify" && len(args) == 1:
+		method, operation = http.MethodPost, "verify"
+	case args[0] == "flush" && len(args) <= 2:
+		method, operation = http.MethodPost, "flush"
+		if len(args) == 2 {
+			body = adminRequest{Channel: args[1]}
+		}
+	case args[0] == "promote" && len(args) == 3:
+		method, operation = http.MethodPost, "promote"
+		body = adminRequest{Channel: args[1], Version: args[2]}
+	case args[0] == "rollback" && len(args) == 2:
+		method, operation = http.MethodPost, "rollback"
+		body = adminRequest{Channel: args[1]}
+	case args[0] == "prepare" && len(args) == 3:
+		method, operation = http.MethodPost, "prepare"
+		body = adminRequest{Channel: args[1], Version: args[2]}
+	case (args[0] == "switch" || args[0] == "unslot") && len(args) == 2:
+		method, operation = http.MethodPost, args[0]
+		body = adminRequest{Channel: args[1]}
+	case args[0] == "yank" && len(args) == 2:
+		method, operation = http.MethodPost, "yank"
+		body = adminRequest{Version: args[1]}
+	case args[0] == "yank" && len(args) == 3:
+		method, operation = http.MethodPost, "yank"
+		body = adminRequest{Product: args[1], Version: args[2]}
+	case args[0] == "unyank" && len(args) == 3:
+		method, operation = http.MethodPost, "unyank"
+		body = adminRequest{Product: args[1], Version: args[2]}
+	case args[0] == "sign" && (len(args) == 3 || len(args) == 4):
+		ttl, err := time.ParseDuration(args[2])
+		if err != nil {
+			return fmt.Errorf("Invalid duration %q", args[2])
+		}
+		method, operation = http.MethodPost, "sign"
+		req := adminRequest{Channel: args[1], TtlSeconds: int(ttl.Seconds())}
+		if len(args) == 4 {
+			req.File = args[3]
+		}
+		body = req
+	case args[0] == "bundle" && (len(args) == 4 || len(args) == 5):
+		method, operation = http.MethodPost, "bundle"
+		req := adminRequest{Channel: args[1], Version: args[2]}
+		if req.Version == "latest" {
+			req.Version = ""
+		}
+		if len(args) == 5 {
+			req.BaseUrl = args[4]
+		}
+		body, output = req, args[3]
+	case args[0] == "import" && len(args) == 2:
+		method, operation, upload = http.MethodPost, "import", args[1]
+	case args[0] == "rollout" && len(args) == 4:
+		percent, err := strconv.Atoi(strings.TrimSuffix(args[3], "%"))
+		if err != nil || percent < 0 || percent > 100 {
+			return fmt.Errorf("Invalid rollout percentage %q", args[3])
+		}
+		method, operation = http.MethodPost, "rollout"
+		body = adminRequest{Channel: args[1], Version: args[2], Percentage: percent}
+	default:
+		fs.Usage()
+		return fmt.Errorf("Invalid admin command")
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	if upload != "" {
+		file, err := os.Open(upload)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reqBody = file
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(*server, "/")+"/admin/"+operation, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	} else if upload != "" {
+		req.Header.Set("Content-Type", "application/zip")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Admin API error: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if output != "" {
+		if err := os.WriteFile(output, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s (%d bytes)\n", output, len(data))
+		return nil
+	}
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, data, "", "  ") == nil {
+		data = pretty.Bytes()
+	}
+	fmt.Println(strings.TrimSpace(string(data)))
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+type AnomalyConfig struct {
+	SpikeFactor          float64 `json:"spikeFactor"`
+	DropFactor           float64 `json:"dropFactor"`
+	MinRequestsPerMinute float64 `json:"minRequestsPerMinute"`
+}
+
+const (
+	// anomalyBaselineWeight is the EWMA weight of each new per-minute sample,
+	// giving a baseline that follows traffic over roughly an hour.
+	anomalyBaselineWeight = 1.0 / 60
+	anomalyWarmupSamples  = 30
+)
+
+type trafficBaseline struct {
+	lastTotal int64
+	rate      float64
+	samples   int
+}
+
+// trafficMonitor compares each channel's requests in the last interval to a
+// moving baseline. Sudden spikes usually mean a launcher retry loop, sudden
+// drops an outage in front of this server.
+type trafficMonitor struct {
+	mu        sync.Mutex
+	baselines map[string]*trafficBaseline
+}
+
+var traffic = &trafficMonitor{baselines: make(map[string]*trafficBaseline)}
+
+func (m *trafficMonitor) Check(cfg AnomalyConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var checks int64
+	defer func() { checksLastMinute.Store(checks) }()
+	for _, ch := range allChannels() {
+		key := ch.Key()
+		total := channelRequests(key)
+		b, ok := m.baselines[key]
+		if !ok {
+			m.baselines[key] = &trafficBaseline{lastTotal: total}
+			continue
+		}
+		current := float64(total - b.lastTotal)
+		checks += total - b.lastTotal
+		b.lastTotal = total
+		if reason := b.anomaly(current, cfg); reason != "" {
+			events.Publish(Event{Type: EventTrafficAnomaly, Channel: key, Reason: reason})
+		}
+		if b.samples == 0 {
+			b.rate = current
+		} else {
+			b.rate += anomalyBaselineWeight * (current - b.rate)
+		}
+		b.samples++
+	}
+}
+
+func (b *trafficBaseline) anomaly(current float64, cfg AnomalyConfig) string {
+	if b.samples < anomalyWarmupSamples {
+		return ""
+	}
+	threshold := math.Max(b.rate, cfg.MinRequestsPerMinute)
+	if cfg.SpikeFactor > 0 && current > threshold*cfg.SpikeFactor {
+		return fmt.Sprintf("request spike: %.0f requests in the last minute, baseline %.1f", current, b.rate)
+	}
+	if cfg.DropFactor > 0 && b.rate >= cfg.MinRequestsPerMinute && current < b.rate/cfg.DropFactor {
+		return fmt.Sprintf("request drop: %.0f requests in the last minute, baseline %.1f", current, b.rate)
+	}
+	return ""
+}
+
+func detectTrafficAnomalies() error {
+	traffic.Check(config().Anomaly)
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The appcast lets native macOS and Windows wrappers of the launcher update
+// through Sparkle or WinSparkle. Each wrapper is an asset role named
+// "sparkle-{os}", e.g. "sparkle-macos" with {"classifier": "macos",
+// "extension": "zip"}, and its EdDSA signature, as printed by
+// "sign_update -p", is uploaded with ".sig" appended to the extension.
+// encoding/xml has no namespace prefixes, so the sparkle: names are spelled
+// out and the namespace declared by hand.
+
+const (
+	sparkleNamespace   = "http://www.andymatuschak.org/xml-namespaces/sparkle"
+	appcastContentType = "application/rss+xml; charset=utf-8"
+	sparkleRolePrefix  = "sparkle-"
+)
+
+// sparkleMediaTypes are the enclosure types of the bundle formats Sparkle
+// and WinSparkle install.
+var sparkleMediaTypes = map[string]string{
+	".zip": "application/zip",
+	".dmg": "application/x-apple-diskimage",
+	".exe": "application/vnd.microsoft.portable-executable",
+	".msi": "application/x-msi",
+}
+
+type appcastEnclosure struct {
+	Url         string `xml:"url,attr"`
+	Length      int64  `xml:"length,attr"`
+	Type        string `xml:"type,attr"`
+	Os          string `xml:"sparkle:os,attr"`
+	EdSignature string `xml:"sparkle:edSignature,attr"`
+}
+
+type appcastItem struct {
+	Title              string           `xml:"title"`
+	PubDate            string           `xml:"pubDate,omitempty"`
+	Version            string           `xml:"sparkle:version"`
+	ShortVersionString string           `xml:"sparkle:shortVersionString"`
+	Description        *appcastCdata    `xml:"description,omitempty"`
+	Enclosure          appcastEnclosure `xml:"enclosure"`
+}
+
+type appcastCdata struct {
+	Body string `xml:",cdata"`
+}
+
+type appcastChannel struct {
+	Title string        `xml:"title"`
+	Link  string        `xml:"link"`
+	Items []appcastItem `xml:"item"`
+}
+
+type appcastRss struct {
+	XMLName      xml.Name       `xml:"rss"`
+	Version      string         `xml:"version,attr"`
+	SparkleXmlns string         `xml:"xmlns:sparkle,attr"`
+	Channel      appcastChannel `xml:"channel"`
+}
+
+func appcastHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	data, err := feeds.Do(ch.Key()+"/appcast.xml", manifestCacheTTL, func() ([]byte, error) {
+		return renderAppcast(ch)
+	})
+	if err != nil {
+		writeFailure(w, "Failed to build appcast", err)
+		return
+	}
+	writeBody(w, appcastContentType, data)
+}
+
+// renderAppcast lists the same versions as the Atom feed, with an item per
+// signed wrapper bundle of each version. Bundles without a signature or a
+// known length are left out, as Sparkle rejects the update otherwise.
+func renderAppcast(ch channel) ([]byte, error) {
+	versions, err := channelVersions(ch)
+	if err != nil {
+		return nil, err
+	}
+	appcast := appcastRss{
+		Version:      "2.0",
+		SparkleXmlns: sparkleNamespace,
+		Channel: appcastChannel{
+			Title: channelTitle(ch),
+			Link:  "/" + ch.Key() + "/appcast.xml",
+		},
+	}
+	for _, manifest := range recentManifests(ch, versions, "appcast") {
+		enclosures := appcastEnclosures(ch, manifest)
+		if len(enclosures) == 0 {
+			continue
+		}
+		item := appcastItem{
+			Title:              ch.Product + " " + manifest.Version,
+			Version:            manifest.Version,
+			ShortVersionString: manifest.Version,
+		}
+		if pubDate, err := time.Parse(time.RFC3339, manifest.PubDate); err == nil {
+			item.PubDate = pubDate.Format(time.RFC1123Z)
+		}
+		if notes := releaseNotesHtml(ch, manifest); notes != "" {
+			item.Description = &appcastCdata{Body: notes}
+		}
+		for _, enclosure := range enclosures {
+			item.Enclosure = enclosure
+			appcast.Channel.Items = append(appcast.Channel.Items, item)
+		}
+	}
+	data, err := xml.MarshalIndent(appcast, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// appcastEnclosures returns the signed wrapper bundles of a version, ordered
+// by OS.
+func appcastEnclosures(ch channel, manifest UpdaterResponse) []appcastEnclosure {
+	var enclosures []appcastEnclosure
+	for _, role := range slices.Sorted(maps.Keys(manifest.Assets)) {
+		target, ok := strings.CutPrefix(role, sparkleRolePrefix)
+		if !ok {
+			continue
+		}
+		url := manifest.Assets[role]
+		signature, err := fetchBundleSignature(url + ".sig")
+		if err != nil {
+			log.Printf("Warning: leaving %s %s %s out of the appcast: %v", ch.Key(), manifest.Version, target, err)
+			continue
+		}
+		length, err := contentLength(url)
+		if err != nil {
+			log.Printf("Warning: leaving %s %s %s out of the appcast: %v", ch.Key(), manifest.Version, target, err)
+			continue
+		}
+		mediaType, ok := sparkleMediaTypes[path.Ext(strings.SplitN(url, "?", 2)[0])]
+		if !ok {
+			mediaType = "application/octet-stream"
+		}
+		enclosures = append(enclosures, appcastEnclosure{Url: url, Length: length, Type: mediaType, Os: target, EdSignature: signature})
+	}
+	return enclosures
+}
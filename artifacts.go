@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type ArtifactConfig struct {
+	Group    string `json:"group" required:"true"`
+	Artifact string `json:"artifact" required:"true"`
+	Disabled bool   `json:"disabled,omitempty"`
+	// RequiredAssets lists the "classifier.extension" assets a version must
+	// have before it is served, e.g. "libraries.json" or "dist.jar.asc".
+	RequiredAssets []string `json:"requiredAssets,omitempty"`
+	// Assets maps roles to selectors, overriding or extending the defaults.
+	Assets map[string]AssetSelector `json:"assets,omitempty"`
+	// Branches maps this product's branches to Nexus repositories, replacing
+	// the top-level branches for it.
+	Branches map[string]string `json:"branches,omitempty"`
+	// Platforms replaces the default natives rules used for ?os=&arch=.
+	Platforms []PlatformRule `json:"platforms,omitempty"`
+	// MainClass is the entry point getdown launches; getdown.txt is only
+	// served when it is set.
+	MainClass string `json:"mainClass,omitempty"`
+}
+
+var (
+	errArtifactNotRegistered = errors.New("Artifact not registered")
+	errArtifactDisabled      = errors.New("Artifact disabled")
+	errChannelNotFound       = errors.New("Channel not found")
+)
+
+// artifactAllowlist is the set of artifacts the server may resolve. Anything
+// not registered here is treated as nonexistent so the server never proxies
+// arbitrary Nexus coordinates.
+type artifactAllowlist struct {
+	mu        sync.RWMutex
+	artifacts map[string]ArtifactConfig
+}
+
+func newArtifactAllowlist(artifacts map[string]ArtifactConfig) *artifactAllowlist {
+	a := &artifactAllowlist{artifacts: make(map[string]ArtifactConfig)}
+	for name, artifact := range artifacts {
+		a.artifacts[name] = artifact
+	}
+	return a
+}
+
+func (a *artifactAllowlist) Lookup(name string) (ArtifactConfig, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	artifact, ok := a.artifacts[name]
+	if !ok {
+		return ArtifactConfig{}, errArtifactNotRegistered
+	}
+	if artifact.Disabled {
+		return artifact, errArtifactDisabled
+	}
+	return artifact, nil
+}
+
+func (a *artifactAllowlist) Register(name string, artifact ArtifactConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.artifacts[name] = artifact
+}
+
+func (a *artifactAllowlist) Names() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, 0, len(a.artifacts))
+	for name, artifact := range a.artifacts {
+		if !artifact.Disabled {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+var artifacts = newArtifactAllowlist(defaultConfig().Artifacts)
+
+type channel struct {
+	Product        string
+	Branch         string
+	Group          string
+	Artifact       string
+	Repository     string
+	RequiredAssets []string
+	Assets         map[string]AssetSelector
+	Platforms      []PlatformRule
+	// Private channels are only served through signed URLs.
+	Private bool
+}
+
+func (c channel) Key() string {
+	return c.Product + "/" + c.Branch
+}
+
+func lookupChannel(product, branch string) (channel, error) {
+	artifact, err := artifacts.Lookup(product)
+	if err != nil {
+		return channel{}, err
+	}
+	repo, ok := artifactBranches(artifact)[branch]
+	if !ok {
+		return channel{}, errChannelNotFound
+	}
+	return channel{
+		Product:        product,
+		Branch:         branch,
+		Group:          artifact.Group,
+		Artifact:       artifact.Artifact,
+		Repository:     repo,
+		RequiredAssets: artifact.RequiredAssets,
+		Assets:         mergeAssetSelectors(artifact.Assets),
+		Platforms:      platformRules(artifact),
+		Private:        slices.Contains(config().PrivateChannels, product+"/"+branch),
+	}, nil
+}
+
+// hasChannel reports whether cfg serves a channel, so references to channels
+// can be checked before cfg is applied.
+func (cfg Config) hasChannel(key string) bool {
+	product, branch, _ := strings.Cut(key, "/")
+	artifact, ok := cfg.Artifacts[product]
+	if !ok || artifact.Disabled {
+		return false
+	}
+	branches := artifact.Branches
+	if len(branches) == 0 {
+		branches = cfg.Branches
+	}
+	_, ok = branches[branch]
+	return ok
+}
+
+func artifactBranches(artifact ArtifactConfig) map[string]string {
+	if len(artifact.Branches) > 0 {
+		return artifact.Branches
+	}
+	return config().Branches
+}
+
+func platformRules(artifact ArtifactConfig) []PlatformRule {
+	if len(artifact.Platforms) > 0 {
+		return artifact.Platforms
+	}
+	return defaultPlatformRules
+}
+
+// legacyProduct is the product that admin and hook requests from before
+// multi-product routing refer to, as they only name a branch.
+const legacyProduct = "selene-client"
+
+// legacyChannelKey turns the branch of a request from before multi-product
+// routing, given either in its own field or as the channel, into a channel
+// key of legacyProduct.
+func legacyChannelKey(key, branch string) string {
+	if key == "" {
+		key = branch
+	}
+	if key != "" && !strings.Contains(key, "/") {
+		return legacyProduct + "/" + key
+	}
+	return key
+}
+
+func parseChannelKey(key string) (channel, error) {
+	product, branch, ok := strings.Cut(key, "/")
+	if !ok {
+		return channel{}, errChannelNotFound
+	}
+	return lookupChannel(product, branch)
+}
+
+func allChannels() []channel {
+	var channels []channel
+	for _, product := range artifacts.Names() {
+		artifact, _ := artifacts.Lookup(product)
+		repos := artifactBranches(artifact)
+		branches := make([]string, 0, len(repos))
+		for branch := range repos {
+			branches = append(branches, branch)
+		}
+		slices.Sort(branches)
+		for _, branch := range branches {
+			if ch, err := lookupChannel(product, branch); err == nil {
+				channels = append(channels, ch)
+			}
+		}
+	}
+	return channels
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AssetSelector matches Nexus assets by classifier and extension. Both are
+// path.Match patterns, so "installer-*" or "tar.*" work.
+type AssetSelector struct {
+	Classifier string `json:"classifier"`
+	Extension  string `json:"extension"`
+}
+
+func (s AssetSelector) matches(asset nexusAsset) bool {
+	classifierOk, _ := path.Match(s.Classifier, asset.Maven2.Classifier)
+	extensionOk, _ := path.Match(s.Extension, asset.Maven2.Extension)
+	return classifierOk && extensionOk
+}
+
+// Built-in asset roles. The resolver serves "primary" as the download and
+// "libraries" as the dependency list; every other configured role is passed
+// through to launchers that declare the "assets" capability.
+const (
+	rolePrimary   = "primary"
+	roleLibraries = "libraries"
+	roleNotes     = "notes"
+)
+
+var defaultAssetSelectors = map[string]AssetSelector{
+	rolePrimary:   {Classifier: "dist", Extension: "jar"},
+	roleLibraries: {Classifier: "libraries", Extension: "json"},
+	roleNotes:     {Classifier: "changelog", Extension: "md"},
+}
+
+func mergeAssetSelectors(configured map[string]AssetSelector) map[string]AssetSelector {
+	selectors := make(map[string]AssetSelector, len(defaultAssetSelectors)+len(configured))
+	for role, s := range defaultAssetSelectors {
+		selectors[role] = s
+	}
+	for role, s := range configured {
+		selectors[role] = s
+	}
+	return selectors
+}
+
+func (item nexusItem) findRole(selectors map[string]AssetSelector, role string) (nexusAsset, bool) {
+	s, ok := selectors[role]
+	if !ok {
+		return nexusAsset{}, false
+	}
+	for _, asset := range item.Assets {
+		if s.matches(asset) {
+			return asset, true
+		}
+	}
+	return nexusAsset{}, false
+}
+
+// extraRoles lists the configured roles that are not handled by the
+// resolver itself, in a stable order.
+func extraRoles(selectors map[string]AssetSelector) []string {
+	var roles []string
+	for role := range selectors {
+		if _, builtin := defaultAssetSelectors[role]; !builtin {
+			roles = append(roles, role)
+		}
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+// defaultRequiredAssets is the completeness contract for artifacts that do
+// not configure one: a version is publishable once its primary asset exists.
+var defaultRequiredAssets = []string{rolePrimary}
+
+// parseAssetName splits "classifier.extension" at the first dot, so signature
+// assets can be named like "dist.jar.asc" and unclassified ones like ".pom".
+func parseAssetName(name string) (classifier, extension string) {
+	classifier, extension, _ = strings.Cut(name, ".")
+	return classifier, extension
+}
+
+// missingAssets checks the completeness contract. Each required entry is
+// either a role name or a literal "classifier.extension".
+func missingAssets(item nexusItem, selectors map[string]AssetSelector, required []string) []string {
+	if required == nil {
+		required = defaultRequiredAssets
+	}
+	var missing []string
+	for _, name := range required {
+		var ok bool
+		if _, isRole := selectors[name]; isRole {
+			_, ok = item.findRole(selectors, name)
+		} else {
+			_, ok = item.findAsset(parseAssetName(name))
+		}
+		if !ok {
+			missing = append(missing, name)
+		}
+	}
+	if _, ok := findProvenanceAsset(item); config().RequireProvenance && !ok {
+		missing = append(missing, "provenance")
+	}
+	return missing
+}
+
+func checkAssetContract(item nexusItem, selectors map[string]AssetSelector, required []string) error {
+	if missing := missingAssets(item, selectors, required); len(missing) > 0 {
+		return fmt.Errorf("%w: version %s is missing required assets %s", errNoRelease, item.Version, strings.Join(missing, ", "))
+	}
+	return nil
+}
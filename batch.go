@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const maxBatchChannels = 16
+
+type sharedLibrary struct {
+	Url      string   `json:"url"`
+	Channels []string `json:"channels"`
+}
+
+type batchResponse struct {
+	Manifests       map[string]UpdaterResponse `json:"manifests"`
+	SharedLibraries map[string]sharedLibrary   `json:"sharedLibraries"`
+	Errors          map[string]string          `json:"errors,omitempty"`
+}
+
+// markSharedLibraries finds libraries that appear with the same URL in more
+// than one manifest so launchers can download them once.
+func markSharedLibraries(manifests map[string]UpdaterResponse) map[string]sharedLibrary {
+	shared := make(map[string]sharedLibrary)
+	for key, manifest := range manifests {
+		for fileName, url := range manifest.Libraries {
+			lib, ok := shared[fileName]
+			if ok && lib.Url != url {
+				continue
+			}
+			lib.Url = url
+			lib.Channels = append(lib.Channels, key)
+			shared[fileName] = lib
+		}
+	}
+	for fileName, lib := range shared {
+		if len(lib.Channels) < 2 {
+			delete(shared, fileName)
+			continue
+		}
+		slices.Sort(lib.Channels)
+	}
+	return shared
+}
+
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	keys := strings.Split(r.URL.Query().Get("channels"), ",")
+	if len(keys) == 0 || keys[0] == "" {
+		http.Error(w, "Missing channels", http.StatusBadRequest)
+		return
+	}
+	if len(keys) > maxBatchChannels {
+		http.Error(w, "Too many channels", http.StatusBadRequest)
+		return
+	}
+	resp := batchResponse{Manifests: make(map[string]UpdaterResponse)}
+	for _, key := range keys {
+		ch, err := parseChannelKey(key)
+		if err == nil && ch.Private {
+			err = errChannelNotFound
+		}
+		if err != nil {
+			status := classifyFailure(err).Status
+			http.Error(w, http.StatusText(status)+": "+key, status)
+			return
+		}
+		manifest, err := resolveChannel(ch)
+		if err != nil {
+			log.Printf("Warning: failed to resolve %s in batch: %v", key, err)
+			if resp.Errors == nil {
+				resp.Errors = make(map[string]string)
+			}
+			resp.Errors[key] = "Failed to fetch latest version"
+			continue
+		}
+		resp.Manifests[ch.Key()] = manifest
+	}
+	resp.SharedLibraries = markSharedLibraries(resp.Manifests)
+	writeJsonResponse(w, "batch", resp)
+}
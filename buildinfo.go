@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X main.buildVersion=1.4.0 -X main.buildCommit=$(git rev-parse HEAD) \
+//		-X main.buildDate=$(date -u +%FT%TZ) -X main.buildFeatures=mirror,bundles"
+//
+// Commit and date fall back to the VCS stamp Go embeds in builds from a
+// checkout.
+var (
+	buildVersion  = "dev"
+	buildCommit   string
+	buildDate     string
+	buildFeatures string
+)
+
+type buildInfo struct {
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit,omitempty"`
+	Date      string   `json:"date,omitempty"`
+	Modified  bool     `json:"modified,omitempty"`
+	GoVersion string   `json:"goVersion"`
+	Features  []string `json:"features"`
+}
+
+func currentBuild() buildInfo {
+	info := buildInfo{Version: buildVersion, Commit: buildCommit, Date: buildDate, GoVersion: runtime.Version(), Features: []string{}}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	for _, feature := range strings.Split(buildFeatures, ",") {
+		if feature = strings.TrimSpace(feature); feature != "" {
+			info.Features = append(info.Features, feature)
+		}
+	}
+	return info
+}
+
+func (b buildInfo) String() string {
+	s := "selene-update-server " + b.Version
+	if b.Commit != "" {
+		s += " (" + b.Commit[:min(len(b.Commit), 12)]
+		if b.Modified {
+			s += "+dirty"
+		}
+		s += ")"
+	}
+	if b.Date != "" {
+		s += " built " + b.Date
+	}
+	return s + " " + b.GoVersion
+}
+
+func buildInfoHandler(w http.ResponseWriter, r *http.Request) {
+	writeAdminJson(w, currentBuild())
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// An offline bundle packages one release for LAN or air-gapped installs:
+//
+//	latest.json      the manifest, pointing at the files below
+//	latest.json.sig  Ed25519 signature of latest.json, if a signing key is set
+//	SHA256SUMS       checksums of every file, in sha256sum format
+//	bundle.json      what the bundle contains and who signed it
+//	files/           the client jar
+//	libraries/       every library
+//
+// Unpacked and served by any static web server, latest.json works as is.
+
+var bundleClient = &http.Client{Timeout: 5 * time.Minute}
+
+type bundleInfo struct {
+	Product   string    `json:"product"`
+	Branch    string    `json:"branch"`
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	PublicKey string    `json:"publicKey,omitempty"`
+}
+
+// bundleUrl is where a bundled file is found relative to latest.json, or
+// under baseUrl when one is given.
+func bundleUrl(baseUrl, name string) string {
+	if baseUrl == "" {
+		return name
+	}
+	return strings.TrimSuffix(baseUrl, "/") + "/" + name
+}
+
+func writeBundle(w io.Writer, ch channel, manifest UpdaterResponse, baseUrl string, key ed25519.PrivateKey) error {
+	zw := zip.NewWriter(w)
+	var sums []string
+	addFile := func(name, url, expected string) error {
+		resp, err := downloadDo(bundleClient, http.MethodGet, url)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Downloading %s returned %s", url, resp.Status)
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(f, hash), resp.Body); err != nil {
+			return err
+		}
+		sum := hex.EncodeToString(hash.Sum(nil))
+		if expected != "" && sum != expected {
+			return fmt.Errorf("Checksum mismatch for %s", url)
+		}
+		sums = append(sums, sum+"  "+name)
+		return nil
+	}
+	addBytes := func(name string, data []byte) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+
+	bundled := manifest
+	jarName := "files/" + path.Base(manifest.FileName)
+	if err := addFile(jarName, manifest.Url, manifest.Sha256); err != nil {
+		return err
+	}
+	bundled.Url = bundleUrl(baseUrl, jarName)
+	bundled.Assets = nil
+	bundled.Libraries = make(map[string]string, len(manifest.Libraries))
+	fileNames := make([]string, 0, len(manifest.Libraries))
+	for fileName := range manifest.Libraries {
+		fileNames = append(fileNames, fileName)
+	}
+	slices.Sort(fileNames)
+	for _, fileName := range fileNames {
+		name := "libraries/" + path.Base(fileName)
+		if err := addFile(name, manifest.Libraries[fileName], manifest.LibrarySha256[fileName]); err != nil {
+			return err
+		}
+		bundled.Libraries[fileName] = bundleUrl(baseUrl, name)
+	}
+
+	data, err := json.MarshalIndent(bundled, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addBytes("latest.json", data); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	sums = append(sums, hex.EncodeToString(sum[:])+"  latest.json")
+	info := bundleInfo{Product: ch.Product, Branch: ch.Branch, Version: manifest.Version, CreatedAt: clock.Now().UTC()}
+	if key != nil {
+		info.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+		if err := addBytes("latest.json.sig", []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))+"\n")); err != nil {
+			return err
+		}
+	}
+	if err := addBytes("SHA256SUMS", []byte(strings.Join(sums, "\n")+"\n")); err != nil {
+		return err
+	}
+	infoData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addBytes("bundle.json", infoData); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type lruEntry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+	size    int64
+}
+
+type lruCache[K comparable, V any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	evictions  int64
+	order      *list.List
+	entries    map[K]*list.Element
+}
+
+func newLruCache[K comparable, V any](maxEntries int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[K]*list.Element),
+	}
+}
+
+// CacheLimitConfig overrides the size limits of a named cache. Zero keeps
+// the built-in entry limit and leaves the size in bytes unbounded.
+type CacheLimitConfig struct {
+	MaxEntries int   `json:"maxEntries"`
+	MaxBytes   int64 `json:"maxBytes"`
+}
+
+type cacheStats struct {
+	Entries    int   `json:"entries"`
+	Bytes      int64 `json:"bytes"`
+	Evictions  int64 `json:"evictions"`
+	MaxEntries int   `json:"maxEntries,omitempty"`
+	MaxBytes   int64 `json:"maxBytes,omitempty"`
+}
+
+type boundedCache interface {
+	Limit(maxEntries int, maxBytes int64)
+	Stats() cacheStats
+}
+
+// namedCaches are the caches whose limits can be configured and whose stats
+// are exported, by name.
+var namedCaches = make(map[string]boundedCache)
+
+func newNamedLruCache[K comparable, V any](name string, maxEntries int) *lruCache[K, V] {
+	c := newLruCache[K, V](maxEntries)
+	namedCaches[name] = c
+	return c
+}
+
+func validateCacheLimits(limits map[string]CacheLimitConfig) error {
+	for name, limit := range limits {
+		if _, ok := namedCaches[name]; !ok {
+			return fmt.Errorf("Unknown cache %q", name)
+		}
+		if limit.MaxEntries < 0 || limit.MaxBytes < 0 {
+			return fmt.Errorf("Cache limits of %q must not be negative", name)
+		}
+	}
+	return nil
+}
+
+func applyCacheLimits(limits map[string]CacheLimitConfig) {
+	for name, limit := range limits {
+		namedCaches[name].Limit(limit.MaxEntries, limit.MaxBytes)
+	}
+}
+
+// approxSize estimates the memory held by an entry from the size of its
+// JSON encoding. It is meant for bounding caches, not for exact accounting.
+func approxSize(v any) int64 {
+	switch v := v.(type) {
+	case string:
+		return int64(len(v))
+	case []byte:
+		return int64(len(v))
+	case error:
+		return int64(len(v.Error()))
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+const lruEntryOverhead = 64
+
+func (c *lruCache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		if entry.expires.IsZero() || clock.Now().Before(entry.expires) {
+			c.order.MoveToFront(el)
+			return entry.value, true
+		}
+		c.remove(el)
+	}
+	var zero V
+	return zero, false
+}
+
+func (c *lruCache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, 0)
+}
+
+func (c *lruCache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = clock.Now().Add(ttl)
+	}
+	size := lruEntryOverhead + approxSize(key) + approxSize(value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		c.bytes += size - entry.size
+		entry.value = value
+		entry.expires = expires
+		entry.size = size
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, expires: expires, size: size})
+		c.bytes += size
+	}
+	c.evict()
+}
+
+// GetOrCreate returns the entry for key, first storing the value of create
+// if there is none. Both happen under one lock, so concurrent callers for the
+// same key share one entry.
+func (c *lruCache[K, V]) GetOrCreate(key K, create func() V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*lruEntry[K, V])
+		if entry.expires.IsZero() || clock.Now().Before(entry.expires) {
+			c.order.MoveToFront(el)
+			return entry.value
+		}
+		c.remove(el)
+	}
+	value := create()
+	size := lruEntryOverhead + approxSize(key) + approxSize(value)
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value, size: size})
+	c.bytes += size
+	c.evict()
+	return value
+}
+
+// evict drops least recently used entries until the cache is within its
+// limits again.
+func (c *lruCache[K, V]) evict() {
+	for c.order.Len() > 0 && (c.maxEntries > 0 && c.order.Len() > c.maxEntries || c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *lruCache[K, V]) remove(el *list.Element) {
+	entry := el.Value.(*lruEntry[K, V])
+	c.order.Remove(el)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+}
+
+func (c *lruCache[K, V]) Limit(maxEntries int, maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if maxEntries > 0 {
+		c.maxEntries = maxEntries
+	}
+	c.maxBytes = maxBytes
+	c.evict()
+}
+
+func (c *lruCache[K, V]) Stats() cacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cacheStats{Entries: c.order.Len(), Bytes: c.bytes, Evictions: c.evictions, MaxEntries: c.maxEntries, MaxBytes: c.maxBytes}
+}
+
+func (c *lruCache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *lruCache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge drops expired entries that have not been looked up since expiring.
+func (c *lruCache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := clock.Now()
+	for _, el := range c.entries {
+		if expires := el.Value.(*lruEntry[K, V]).expires; !expires.IsZero() && !now.Before(expires) {
+			c.remove(el)
+		}
+	}
+}
+
+func (c *lruCache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+	c.bytes = 0
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const capabilitiesHeader = "X-Selene-Updater-Capabilities"
+
+// Capabilities launchers can declare. Optional manifest fields added after
+// the first launcher release are left out unless a launcher asks for them.
+const (
+	capabilityCompact = "compact"
+	capabilitySha256  = "sha256"
+	capabilityAssets  = "assets"
+	capabilityStale   = "stale"
+	capabilityTheme   = "theme"
+	// capabilityNextCheck asks for nextCheckAfterSeconds.
+	capabilityNextCheck = "next-check"
+)
+
+var supportedCapabilities = []string{capabilityAssets, capabilityCompact, capabilityNextCheck, capabilitySha256, capabilityStale, capabilityTheme}
+
+func parseCapabilities(r *http.Request) map[string]bool {
+	caps := make(map[string]bool)
+	for _, value := range r.Header.Values(capabilitiesHeader) {
+		for _, c := range strings.Split(value, ",") {
+			if c = strings.ToLower(strings.TrimSpace(c)); slices.Contains(supportedCapabilities, c) {
+				caps[c] = true
+			}
+		}
+	}
+	return caps
+}
+
+// negotiateCapabilities reads the launcher's capabilities and echoes back
+// the ones this server honoured.
+func negotiateCapabilities(h http.Header, r *http.Request) map[string]bool {
+	caps := parseCapabilities(r)
+	h.Add("Vary", capabilitiesHeader)
+	honoured := make([]string, 0, len(caps))
+	for _, c := range supportedCapabilities {
+		if caps[c] {
+			honoured = append(honoured, c)
+		}
+	}
+	if len(honoured) > 0 {
+		h.Set(capabilitiesHeader, strings.Join(honoured, ","))
+	}
+	return caps
+}
+
+// tailorManifest drops the optional fields the launcher declared no
+// capability for. That includes the checksums: without sha256 a launcher
+// gets no jar or library hashes to verify its downloads against, so every
+// launcher that can check them should declare it.
+func tailorManifest(resp UpdaterResponse, caps map[string]bool) UpdaterResponse {
+	if !caps[capabilitySha256] {
+		resp.Sha256 = ""
+		resp.LibrarySha256 = nil
+	}
+	if !caps[capabilityAssets] {
+		resp.Assets = nil
+	}
+	if !caps[capabilityStale] {
+		resp.Stale = false
+	}
+	if !caps[capabilityTheme] {
+		resp.Theme = nil
+	}
+	if !caps[capabilityNextCheck] {
+		resp.NextCheckAfterSeconds = 0
+	}
+	return resp
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var releaseNotesCache = newNamedLruCache[string, string]("releaseNotes", 512)
+
+func fetchReleaseNotes(cacheKey, assetUrl string) (string, error) {
+	if notes, ok := releaseNotesCache.Get(cacheKey); ok {
+		return notes, nil
+	}
+	resp, err := upstreamGet(assetUrl)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed to fetch release notes: %w", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode})
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	notes := strings.TrimSpace(string(body))
+	releaseNotesCache.Set(cacheKey, notes)
+	return notes, nil
+}
+
+type changelogRange struct {
+	Channel, From, To string
+}
+
+// changelogMemo deduplicates identical range requests. Ranges that end at the
+// latest version are only kept as long as a manifest, since a new release
+// changes them; bounded ranges never change once published.
+var changelogMemo = newMemoizer[changelogRange, string]("changelog", 256)
+
+// changelogVersions caches each channel's version listing, newest first, so
+// changelog requests do not page through Nexus each time.
+var changelogVersions = newMemoizer[string, []nexusItem]("changelogVersions", 64)
+
+// maxChangelogVersions caps how many releases one changelog covers.
+const maxChangelogVersions = 50
+
+func aggregateChangelog(ch channel, from, to string) (string, error) {
+	items, err := changelogVersions.Do(ch.Key(), manifestCacheTTL, func() ([]nexusItem, error) {
+		items, err := listNexusVersions(ch.Repository, ch.Group, ch.Artifact)
+		slices.SortFunc(items, func(a, b nexusItem) int {
+			return compareVersions(b.Version, a.Version)
+		})
+		return items, err
+	})
+	if err != nil {
+		return "", err
+	}
+	// from and to come from the query, so they are snapped to listed
+	// versions before keying the memo: any pair of values maps onto one
+	// of the ranges between releases.
+	if to != "" {
+		if to = newestVersionUpTo(items, to); to == "" {
+			return "", nil
+		}
+	}
+	from = newestVersionUpTo(items, from)
+	ttl := time.Hour
+	if to == "" {
+		ttl = manifestCacheTTL
+	}
+	return changelogMemo.Do(changelogRange{ch.Key(), from, to}, ttl, func() (string, error) {
+		return computeChangelog(ch, items, from, to)
+	})
+}
+
+// newestVersionUpTo returns the newest of items, sorted newest first, that
+// is not newer than version, or "" if version is empty or older than all.
+func newestVersionUpTo(items []nexusItem, version string) string {
+	if version == "" {
+		return ""
+	}
+	for _, item := range items {
+		if compareVersions(item.Version, version) <= 0 {
+			return item.Version
+		}
+	}
+	return ""
+}
+
+// computeChangelog renders the releases of items, sorted newest first,
+// after from up to and including to, at most maxChangelogVersions of them.
+func computeChangelog(ch channel, items []nexusItem, from, to string) (string, error) {
+	items = slices.DeleteFunc(slices.Clone(items), func(item nexusItem) bool {
+		return (from != "" && compareVersions(item.Version, from) <= 0) || (to != "" && compareVersions(item.Version, to) > 0)
+	})
+	items = items[:min(len(items), maxChangelogVersions)]
+
+	var sb strings.Builder
+	for _, item := range items {
+		var sections []string
+		if asset, ok := item.findRole(ch.Assets, roleNotes); ok {
+			notes, err := fetchReleaseNotes(ch.Group+":"+ch.Artifact+":"+item.Version, transformToPublicUrl(asset.DownloadUrl))
+			if err != nil {
+				log.Printf("Warning: failed to fetch release notes for %s: %v", item.Version, err)
+			} else {
+				sections = append(sections, notes)
+			}
+		}
+		if changes, ok := libraryChangeLog.Get(ch.Group+":"+ch.Artifact, item.Version); ok && !changes.Empty() {
+			sections = append(sections, formatLibraryChanges(changes))
+		}
+		if len(sections) == 0 {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "## %s\n\n%s", item.Version, strings.Join(sections, "\n\n"))
+	}
+	return sb.String(), nil
+}
+
+func prefersHtml(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		switch mediaType {
+		case "text/html":
+			return true
+		case "text/markdown", "text/plain":
+			return false
+		}
+	}
+	return false
+}
+
+func changelogHandler(w http.ResponseWriter, r *http.Request, ch channel, format string) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from != "" && to != "" && compareVersions(from, to) > 0 {
+		http.Error(w, "from must not be newer than to", http.StatusBadRequest)
+		return
+	}
+	changelog, err := aggregateChangelog(ch, from, to)
+	if err != nil {
+		writeFailure(w, "Failed to fetch changelog", err)
+		return
+	}
+	if format == "" {
+		format = "md"
+		if prefersHtml(r) {
+			format = "html"
+		}
+	}
+	w.Header().Add("Vary", "Accept")
+	if format == "html" {
+		writeBody(w, htmlContentType, []byte(renderMarkdown(changelog)))
+		return
+	}
+	writeBody(w, "text/markdown; charset=utf-8", []byte(changelog))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// retiredResponse tells launchers still configured for a retired channel
+// where to go instead.
+type retiredResponse struct {
+	Error          string `json:"error"`
+	Reason         string `json:"reason"`
+	Replacement    string `json:"replacement,omitempty"`
+	ReplacementUrl string `json:"replacementUrl,omitempty"`
+}
+
+func validateRetiredChannels(cfg Config) error {
+	for key, replacement := range cfg.RetiredChannels {
+		if replacement == "" {
+			continue
+		}
+		if _, ok := cfg.RetiredChannels[replacement]; ok {
+			return fmt.Errorf("Retired channel %s is replaced by retired channel %s", key, replacement)
+		}
+		if !cfg.hasChannel(replacement) {
+			return fmt.Errorf("Retired channel %s is replaced by unknown channel %s", key, replacement)
+		}
+	}
+	return nil
+}
+
+// serveRetired answers 410 for a retired channel, pointing at the same file
+// on its replacement if there is one.
+func serveRetired(w http.ResponseWriter, segments []string) bool {
+	resp, ok := retiredChannel(segments)
+	if ok {
+		writeJsonResponseStatus(w, http.StatusGone, "retired", resp)
+	}
+	return ok
+}
+
+func retiredChannel(segments []string) (retiredResponse, bool) {
+	replacement, ok := config().RetiredChannels[segments[0]+"/"+segments[1]]
+	if !ok {
+		return retiredResponse{}, false
+	}
+	resp := retiredResponse{Error: "Channel retired", Reason: "channel_retired", Replacement: replacement}
+	if replacement != "" {
+		resp.ReplacementUrl = "/" + replacement + "/" + strings.Join(segments[2:], "/")
+	}
+	return resp, true
+}
+
+type channelSummary struct {
+	Branch  string        `json:"branch"`
+	Theme   *ChannelTheme `json:"theme,omitempty"`
+	Version string        `json:"version,omitempty"`
+	PubDate string        `json:"pub_date,omitempty"`
+	Stale   bool          `json:"stale,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type channelList struct {
+	Product  string           `json:"product"`
+	Channels []channelSummary `json:"channels"`
+}
+
+// channelsHandler lists a product's public channels so launchers can build
+// their channel picker without hard-coding branches.
+func channelsHandler(w http.ResponseWriter, r *http.Request, product string) {
+	if _, err := artifacts.Lookup(product); err != nil {
+		writeFailure(w, "", err)
+		return
+	}
+	list := channelList{Product: product, Channels: []channelSummary{}}
+	for _, ch := range allChannels() {
+		if _, retired := config().RetiredChannels[ch.Key()]; ch.Product != product || ch.Private || retired {
+			continue
+		}
+		summary := channelSummary{Branch: ch.Branch, Theme: channelTheme(ch)}
+		if resp, err := resolveChannel(ch); err != nil {
+			log.Printf("Warning: failed to resolve %s for channel list: %v", ch.Key(), err)
+			summary.Error = "Failed to fetch latest version"
+		} else {
+			summary.Version, summary.PubDate, summary.Stale = resp.Version, resp.PubDate, resp.Stale
+		}
+		list.Channels = append(list.Channels, summary)
+	}
+	writeJsonResponse(w, "channels", list)
+}
+
+type artifactChannel struct {
+	Branch string        `json:"branch"`
+	Theme  *ChannelTheme `json:"theme,omitempty"`
+}
+
+type artifactSummary struct {
+	Product  string            `json:"product"`
+	Group    string            `json:"group"`
+	Artifact string            `json:"artifact"`
+	Channels []artifactChannel `json:"channels"`
+}
+
+// artifactsHandler lists every enabled product and its public channels, so
+// tooling can see what a deployment serves.
+func artifactsHandler(w http.ResponseWriter, r *http.Request) {
+	list := []artifactSummary{}
+	for _, ch := range allChannels() {
+		if len(list) == 0 || list[len(list)-1].Product != ch.Product {
+			list = append(list, artifactSummary{Product: ch.Product, Group: ch.Group, Artifact: ch.Artifact, Channels: []artifactChannel{}})
+		}
+		if _, retired := config().RetiredChannels[ch.Key()]; !ch.Private && !retired {
+			summary := &list[len(list)-1]
+			summary.Channels = append(summary.Channels, artifactChannel{Branch: ch.Branch, Theme: channelTheme(ch)})
+		}
+	}
+	writeJsonResponse(w, "artifacts", list)
+}
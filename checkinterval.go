@@ -0,0 +1,37 @@
+package main
+
+import "sync/atomic"
+
+type CheckIntervalConfig struct {
+	// Seconds maps channels (selene-client/stable) or branches (stable) to
+	// how long launchers should wait before checking again.
+	Seconds map[string]int `json:"seconds"`
+	// MaxChecksPerMinute raises the intervals in proportion while the whole
+	// fleet checks more often than this, up to maxCheckIntervalFactor.
+	MaxChecksPerMinute int `json:"maxChecksPerMinute"`
+}
+
+const maxCheckIntervalFactor = 8
+
+// checksLastMinute is the number of update checks the traffic monitor
+// counted across all channels in its last sample.
+var checksLastMinute atomic.Int64
+
+// nextCheckAfter suggests how many seconds a launcher on ch should wait
+// before its next update check, or 0 when no interval is configured.
+func nextCheckAfter(ch channel) int {
+	cfg := config().CheckInterval
+	seconds, ok := cfg.Seconds[ch.Key()]
+	if !ok {
+		seconds = cfg.Seconds[ch.Branch]
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	if limit := int64(cfg.MaxChecksPerMinute); limit > 0 {
+		if checks := checksLastMinute.Load(); checks > limit {
+			seconds = int(min(int64(seconds)*checks/limit, int64(seconds)*maxCheckIntervalFactor))
+		}
+	}
+	return seconds
+}
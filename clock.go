@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/binary"
+	"expvar"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ClockConfig enables correcting the host clock against an NTP server. It is
+// off unless ntpServer is set, as the server makes no outbound NTP traffic by
+// default.
+type ClockConfig struct {
+	NtpServer            string `json:"ntpServer"`
+	SkewToleranceSeconds int    `json:"skewToleranceSeconds"`
+	CheckIntervalMinutes int    `json:"checkIntervalMinutes"`
+}
+
+// Clock is the time source for cache expiry, signing timestamps and embargo
+// checks. Everything that compares against wall time goes through it so a
+// drifting host clock is corrected in one place.
+type Clock interface {
+	Now() time.Time
+}
+
+var clockDrift = expvar.NewFloat("clock_drift_seconds")
+
+type correctedClock struct {
+	offset    atomic.Int64
+	tolerance time.Duration
+}
+
+func newCorrectedClock(tolerance time.Duration) *correctedClock {
+	return &correctedClock{tolerance: tolerance}
+}
+
+func (c *correctedClock) Now() time.Time {
+	return time.Now().Add(time.Duration(c.offset.Load()))
+}
+
+// Reached reports whether t has passed, giving the benefit of the doubt
+// within the configured skew tolerance.
+func (c *correctedClock) Reached(t time.Time) bool {
+	return !c.Now().Add(c.tolerance).Before(t)
+}
+
+// Observe records the host clock's offset from a reference. Offsets within
+// the skew tolerance are ignored; larger ones are logged and corrected for.
+func (c *correctedClock) Observe(offset time.Duration) {
+	clockDrift.Set(offset.Seconds())
+	if offset.Abs() <= c.tolerance {
+		if c.offset.Swap(0) != 0 {
+			log.Printf("Host clock back within %s of NTP, clearing correction", c.tolerance)
+		}
+		return
+	}
+	log.Printf("Warning: host clock is off by %s (tolerance %s), correcting", -offset, c.tolerance)
+	c.offset.Store(int64(offset))
+}
+
+var clock = newCorrectedClock(time.Duration(defaultConfig().Clock.SkewToleranceSeconds) * time.Second)
+
+// ntpEpochOffset is the number of seconds between 1900-01-01 and 1970-01-01.
+const ntpEpochOffset = 2208988800
+
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(secs, (frac*1e9)>>32)
+}
+
+// queryNtpOffset asks an NTP server for the offset that has to be added to
+// the local clock to match it (SNTP, RFC 4330).
+func queryNtpOffset(server string) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(server, "123"), 5*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := make([]byte, 48)
+	req[0] = 0x1B // LI 0, version 3, client mode
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	if _, err := conn.Read(resp); err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	serverReceive, serverTransmit := ntpTime(resp[32:40]), ntpTime(resp[40:48])
+	return (serverReceive.Sub(sent) + serverTransmit.Sub(received)) / 2, nil
+}
+
+func monitorClockDrift(cfg ClockConfig) {
+	if cfg.NtpServer == "" || cfg.CheckIntervalMinutes <= 0 {
+		return
+	}
+	for {
+		offset, err := queryNtpOffset(cfg.NtpServer)
+		if err != nil {
+			log.Printf("Warning: failed to query NTP server %s: %v", cfg.NtpServer, err)
+		} else {
+			clock.Observe(offset)
+		}
+		time.Sleep(time.Duration(cfg.CheckIntervalMinutes) * time.Minute)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// compactResponse is the ?compact=1 manifest for launchers on slow or
+// metered connections. Paths are relative to BaseUrl unless they are
+// absolute URLs, and file names are the last path segment.
+type compactResponse struct {
+	Version   string   `json:"v"`
+	PubDate   string   `json:"d,omitempty"`
+	BaseUrl   string   `json:"b"`
+	Path      string   `json:"p"`
+	Libraries []string `json:"l,omitempty"`
+	NextCheck int      `json:"n,omitempty"`
+	// Sha256 and LibrarySha256, keyed by file name, are only set for
+	// launchers that declare the sha256 capability.
+	Sha256        string            `json:"s,omitempty"`
+	LibrarySha256 map[string]string `json:"ls,omitempty"`
+}
+
+func relativeToPublicRepository(url string) string {
+	if rel, ok := strings.CutPrefix(url, publicRepositoryUrl()); ok {
+		return rel
+	}
+	return url
+}
+
+func compactManifest(resp UpdaterResponse) compactResponse {
+	compact := compactResponse{
+		Version:       resp.Version,
+		PubDate:       resp.PubDate,
+		BaseUrl:       publicRepositoryUrl(),
+		Path:          relativeToPublicRepository(resp.Url),
+		NextCheck:     resp.NextCheckAfterSeconds,
+		Sha256:        resp.Sha256,
+		LibrarySha256: resp.LibrarySha256,
+	}
+	for _, url := range resp.Libraries {
+		compact.Libraries = append(compact.Libraries, relativeToPublicRepository(url))
+	}
+	slices.Sort(compact.Libraries)
+	return compact
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+type Config struct {
+	Listen                  string                      `json:"listen"`
+	GrpcListen              string                      `json:"grpcListen"`
+	Nexus                   NexusConfig                 `json:"nexus"`
+	Branches                map[string]string           `json:"branches"`
+	SnapshotPath            string                      `json:"snapshotPath"`
+	BlocklistPath           string                      `json:"blocklistPath"`
+	BlockedVersions         map[string][]string         `json:"blockedVersions"`
+	ReleaseHistoryPath      string                      `json:"releaseHistoryPath"`
+	RolloutsPath            string                      `json:"rolloutsPath"`
+	SlotsPath               string                      `json:"slotsPath"`
+	LibraryChangesPath      string                      `json:"libraryChangesPath"`
+	ModWatchesPath          string                      `json:"modWatchesPath"`
+	ModNotifierHosts        []string                    `json:"modNotifierHosts"`
+	CanaryChannel           string                      `json:"canaryChannel"`
+	CacheTtlSeconds         map[string]int              `json:"cacheTtlSeconds"`
+	Themes                  map[string]ChannelTheme     `json:"themes"`
+	RetiredChannels         map[string]string           `json:"retiredChannels"`
+	RefreshIntervalSeconds  int                         `json:"refreshIntervalSeconds"`
+	Caches                  map[string]CacheLimitConfig `json:"caches"`
+	TransparencyLogPath     string                      `json:"transparencyLogPath"`
+	Artifacts               map[string]ArtifactConfig   `json:"artifacts"`
+	Upstream                UpstreamConfig              `json:"upstream"`
+	Notifiers               []NotifierConfig            `json:"notifiers"`
+	DeadLetterPath          string                      `json:"deadLetterPath"`
+	GitPublish              *GitPublishConfig           `json:"gitPublish,omitempty"`
+	EventExport             *EventExportConfig          `json:"eventExport,omitempty"`
+	Mirror                  *MirrorConfig               `json:"mirror,omitempty"`
+	Filesystem              *FilesystemConfig           `json:"filesystem,omitempty"`
+	RequireProvenance       bool                        `json:"requireProvenance"`
+	SettlingMinutes         int                         `json:"settlingMinutes"`
+	RebuildsPath            string                      `json:"rebuildsPath"`
+	Rebuilders              map[string]string           `json:"rebuilders"`
+	Hooks                   map[string]string           `json:"hooks"`
+	AdminToken              string                      `json:"adminToken"`
+	AdminTokens             []AdminTokenConfig          `json:"adminTokens"`
+	Oidc                    *OidcConfig                 `json:"oidc,omitempty"`
+	AccessRules             map[string]AccessRule       `json:"accessRules"`
+	PrivateChannels         []string                    `json:"privateChannels"`
+	UrlSigningSecret        string                      `json:"urlSigningSecret"`
+	SyncSigningKey          string                      `json:"syncSigningKey"`
+	SignManifests           bool                        `json:"signManifests"`
+	SyncFeedPath            string                      `json:"syncFeedPath"`
+	SearchRequestsPerMinute int                         `json:"searchRequestsPerMinute"`
+	ValidateResponses       bool                        `json:"validateResponses"`
+	DebugResponses          bool                        `json:"debugResponses"`
+	DebugToken              string                      `json:"debugToken"`
+	Clock                   ClockConfig                 `json:"clock"`
+	Jobs                    JobsConfig                  `json:"jobs"`
+	Schedules               map[string]string           `json:"schedules"`
+	Anomaly                 AnomalyConfig               `json:"anomaly"`
+	Watchdog                WatchdogConfig              `json:"watchdog"`
+	SmokeTest               SmokeTestConfig             `json:"smokeTest"`
+	DownloadSize            DownloadSizeConfig          `json:"downloadSize"`
+	CheckInterval           CheckIntervalConfig         `json:"checkInterval"`
+}
+
+type UpstreamConfig struct {
+	RequestsPerMinute int `json:"requestsPerMinute"`
+}
+
+// currentConfig is swapped as a whole on reload, so requests in flight
+// keep reading the config they started with.
+var currentConfig atomic.Pointer[Config]
+
+func init() {
+	setConfig(withDefaultBranches(defaultConfig()))
+}
+
+// config returns the current config. It must not be modified; reloads
+// replace it with setConfig.
+func config() *Config {
+	return currentConfig.Load()
+}
+
+func setConfig(cfg Config) {
+	currentConfig.Store(&cfg)
+}
+
+func defaultConfig() Config {
+	return Config{
+		Artifacts: map[string]ArtifactConfig{
+			"selene-client": {Group: "world.selene", Artifact: "selene-client"},
+		},
+		Upstream: UpstreamConfig{
+			RequestsPerMinute: 120,
+		},
+		SearchRequestsPerMinute: 30,
+		SettlingMinutes:         5,
+		Schedules: map[string]string{
+			"gc":       "*/15 * * * *",
+			"traffic":  "* * * * *",
+			"watchdog": "* * * * *",
+		},
+		SmokeTest: SmokeTestConfig{
+			MaxLibraryChangePercent: 50,
+		},
+		Watchdog: WatchdogConfig{
+			MaxGoroutines: 10000,
+			MaxOpenFiles:  1000,
+		},
+		Anomaly: AnomalyConfig{
+			SpikeFactor:          5,
+			DropFactor:           5,
+			MinRequestsPerMinute: 10,
+		},
+		Listen: ":8080",
+		Nexus: NexusConfig{
+			Url:                  "https://maven.twelveiterations.com",
+			PublicRepository:     "selene-public",
+			InternalRepositories: []string{"maven-releases", "maven-snapshots"},
+		},
+		Jobs: JobsConfig{
+			Workers:   4,
+			QueueSize: 256,
+		},
+		Clock: ClockConfig{
+			SkewToleranceSeconds: 5,
+			CheckIntervalMinutes: 60,
+		},
+	}
+}
+
+// defaultBranches maps branches to the Nexus repository they resolve from.
+// It only applies when the config defines no branches, so a config can
+// replace the set instead of merging into it.
+var defaultBranches = map[string]string{
+	"stable":       "maven-snapshots", // TODO for now, until we have a first stable release
+	"experimental": "maven-snapshots",
+}
+
+func profileConfigPath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// loadConfig reads the base config and then, if a profile is given, layers
+// config.<profile>.json on top of it. Objects merge key by key at every
+// depth, including entries of maps such as artifacts, while scalars and
+// arrays in the profile replace the base values.
+func loadConfig(path, profile string) (Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		if profile != "" {
+			return cfg, fmt.Errorf("A config file is required when selecting profile %q", profile)
+		}
+		return withDefaultBranches(cfg), nil
+	}
+	layers := []string{path}
+	if profile != "" {
+		layers = append(layers, profileConfigPath(path, profile))
+	}
+	var merged map[string]any
+	for i, layer := range layers {
+		data, err := os.ReadFile(layer)
+		if err != nil {
+			return cfg, err
+		}
+		validate := validateConfig
+		if i > 0 {
+			validate = validateConfigLayer
+		}
+		if err := validate(layer, data); err != nil {
+			return cfg, err
+		}
+		var doc map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&doc); err != nil {
+			return cfg, err
+		}
+		merged = mergeJsonObjects(merged, doc)
+	}
+	// Round trip the merged document, as unmarshalling each layer in turn
+	// would replace whole map entries instead of merging them.
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return cfg, err
+	}
+	if len(layers) > 1 {
+		// Entries the profile adds must still be complete once merged.
+		if err := validateConfig(path+" with profile "+profile, data); err != nil {
+			return cfg, err
+		}
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, err
+	}
+	return withDefaultBranches(cfg), nil
+}
+
+// mergeJsonObjects layers overlay onto base, merging nested objects and
+// replacing any other value.
+func mergeJsonObjects(base, overlay map[string]any) map[string]any {
+	if base == nil {
+		return overlay
+	}
+	for key, value := range overlay {
+		if baseObject, ok := base[key].(map[string]any); ok {
+			if overlayObject, ok := value.(map[string]any); ok {
+				base[key] = mergeJsonObjects(baseObject, overlayObject)
+				continue
+			}
+		}
+		base[key] = value
+	}
+	return base
+}
+
+func withDefaultBranches(cfg Config) Config {
+	if len(cfg.Branches) == 0 {
+		cfg.Branches = defaultBranches
+	}
+	return cfg
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type configError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *configError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+type configValidator struct {
+	file   string
+	data   []byte
+	dec    *json.Decoder
+	errors []error
+	// partial skips required key checks, for profile layers whose required
+	// keys may come from the base config.
+	partial bool
+}
+
+// validateConfig checks raw config JSON against the shape of Config, so that
+// misspelled keys and wrongly typed values are reported instead of ignored.
+func validateConfig(file string, data []byte) error {
+	return checkConfig(&configValidator{file: file, data: data, dec: json.NewDecoder(bytes.NewReader(data))})
+}
+
+// validateConfigLayer checks a profile layer like validateConfig, except
+// that required keys may be left to the base config.
+func validateConfigLayer(file string, data []byte) error {
+	return checkConfig(&configValidator{file: file, data: data, dec: json.NewDecoder(bytes.NewReader(data)), partial: true})
+}
+
+func checkConfig(v *configValidator) error {
+	v.dec.UseNumber()
+	if err := v.value(reflect.TypeOf(Config{}), ""); err != nil {
+		v.errors = append(v.errors, v.errorAt(v.dec.InputOffset(), "%v", err))
+	}
+	return errors.Join(v.errors...)
+}
+
+func (v *configValidator) errorAt(offset int64, format string, args ...any) error {
+	for int(offset) < len(v.data) && strings.IndexByte(" \t\r\n:,", v.data[offset]) >= 0 {
+		offset++
+	}
+	before := v.data[:min(int(offset), len(v.data))]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return &configError{File: v.file, Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+func (v *configValidator) report(offset int64, path, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if path != "" {
+		msg = fmt.Sprintf("%s: %s", path, msg)
+	}
+	v.errors = append(v.errors, v.errorAt(offset, "%s", msg))
+}
+
+func jsonKindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice:
+		return "an array"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "an integer"
+	case reflect.Float64, reflect.Float32:
+		return "a number"
+	}
+	return t.String()
+}
+
+func (v *configValidator) value(t reflect.Type, path string) error {
+	start := v.dec.InputOffset()
+	tok, err := v.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return v.skip(tok)
+	}
+	mismatch := func() error {
+		v.report(start, path, "expected %s", jsonKindName(t))
+		return v.skip(tok)
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if tok != json.Delim('{') {
+			return mismatch()
+		}
+		return v.object(t, path)
+	case reflect.Map:
+		if tok != json.Delim('{') {
+			return mismatch()
+		}
+		for v.dec.More() {
+			keyTok, err := v.dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := v.value(t.Elem(), joinConfigPath(path, keyTok.(string))); err != nil {
+				return err
+			}
+		}
+		_, err := v.dec.Token()
+		return err
+	case reflect.Slice:
+		if tok != json.Delim('[') {
+			return mismatch()
+		}
+		for i := 0; v.dec.More(); i++ {
+			if err := v.value(t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err := v.dec.Token()
+		return err
+	case reflect.String:
+		if _, ok := tok.(string); !ok {
+			return mismatch()
+		}
+	case reflect.Bool:
+		if _, ok := tok.(bool); !ok {
+			return mismatch()
+		}
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		n, ok := tok.(json.Number)
+		if !ok {
+			return mismatch()
+		}
+		if _, err := n.Int64(); err != nil {
+			v.report(start, path, "expected an integer, got %s", n)
+		}
+	case reflect.Float64, reflect.Float32:
+		if _, ok := tok.(json.Number); !ok {
+			return mismatch()
+		}
+	}
+	return nil
+}
+
+func (v *configValidator) object(t reflect.Type, path string) error {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	seen := make(map[string]bool)
+	for v.dec.More() {
+		keyStart := v.dec.InputOffset()
+		keyTok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := keyTok.(string)
+		field, ok := fields[key]
+		if !ok {
+			for name, candidate := range fields {
+				if strings.EqualFold(name, key) {
+					v.report(keyStart, path, "unknown key %q (did you mean %q?)", key, name)
+					field, ok = candidate, true
+					break
+				}
+			}
+			if !ok {
+				v.report(keyStart, path, "unknown key %q", key)
+				tok, err := v.dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := v.skip(tok); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		seen[name] = true
+		if err := v.value(field.Type, joinConfigPath(path, key)); err != nil {
+			return err
+		}
+	}
+	end := v.dec.InputOffset()
+	if _, err := v.dec.Token(); err != nil {
+		return err
+	}
+	for name, field := range fields {
+		if !v.partial && field.Tag.Get("required") == "true" && !seen[name] {
+			v.report(end, path, "missing required key %q", name)
+		}
+	}
+	return nil
+}
+
+func (v *configValidator) skip(tok json.Token) error {
+	if tok != json.Delim('{') && tok != json.Delim('[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := v.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// validateConfigValues runs the checks the config schema cannot express,
+// against cfg before it is applied, on startup and on every reload.
+func validateConfigValues(cfg Config) error {
+	if err := validateAdminTokens(cfg.AdminTokens); err != nil {
+		return err
+	}
+	if err := validateRetiredChannels(cfg); err != nil {
+		return err
+	}
+	if err := validateChannelThemes(cfg.Themes); err != nil {
+		return err
+	}
+	if err := validateCacheLimits(cfg.Caches); err != nil {
+		return err
+	}
+	for name, artifact := range cfg.Artifacts {
+		if err := validatePlatformRules(artifact.Platforms); err != nil {
+			return fmt.Errorf("artifact %s: %v", name, err)
+		}
+	}
+	if cfg.SyncSigningKey != "" && cfg.Mirror == nil && cfg.SyncFeedPath == "" {
+		// Without it the feed restarts at sequence 1, which replicas
+		// reject as a gap.
+		return fmt.Errorf("syncSigningKey requires syncFeedPath")
+	}
+	for product := range cfg.BlockedVersions {
+		if _, ok := cfg.Artifacts[product]; !ok {
+			return fmt.Errorf("blockedVersions: unknown product %q", product)
+		}
+	}
+	return nil
+}
+
+// reloadConfigOnHangup reloads the config whenever the process receives
+// SIGHUP.
+func reloadConfigOnHangup(path, profile string) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	for range hangups {
+		if err := reloadConfig(path, profile); err != nil {
+			log.Printf("Warning: failed to reload config, keeping the current one:\n%v", err)
+			continue
+		}
+		log.Printf("Reloaded config")
+	}
+}
+
+// reloadConfig validates the config files like on startup and only applies
+// them if they pass. Settings that set up listeners, stores, notifiers,
+// schedules or signing keys are read once at startup and keep their values
+// until a restart; artifacts removed from the config stay registered.
+func reloadConfig(path, profile string) error {
+	cfg, err := loadConfig(path, profile)
+	if err != nil {
+		return err
+	}
+	if err := validateConfigValues(cfg); err != nil {
+		return err
+	}
+	setConfig(cfg)
+	for name, artifact := range cfg.Artifacts {
+		artifacts.Register(name, artifact)
+	}
+	admin.BlockByConfig(cfg)
+	applyCacheLimits(cfg.Caches)
+	events.Publish(Event{Type: EventConfigReloaded})
+	return nil
+}
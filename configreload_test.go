@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestReloadDuringRequests reloads the config while update checks and
+// channel lookups read it; run with -race to catch unsynchronised access.
+func TestReloadDuringRequests(t *testing.T) {
+	server := startFakeNexus(t)
+	useConfig(t, func(cfg *Config) {})
+	cacheTestManifest(t, testManifest())
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				serveTest(httptest.NewRequest("GET", "/selene-client/stable/latest.json", nil))
+				lookupChannel("selene-client", "experimental")
+				allChannels()
+			}
+		}()
+	}
+	for i := range 20 {
+		private := ""
+		if i%2 == 0 {
+			private = `"selene-client/experimental"`
+		}
+		data := fmt.Sprintf(`{"nexus": {"url": %q}, "privateChannels": [%s]}`, server.URL, private)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if err := reloadConfig(path, ""); err != nil {
+			t.Fatalf("reloadConfig: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if ch, err := lookupChannel("selene-client", "experimental"); err != nil || ch.Private {
+		t.Errorf("lookupChannel after the last reload = %+v, %v, want a public channel", ch, err)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week).
+type cronSchedule struct {
+	minute, hour, dom, month, dow []bool
+	domAny, dowAny                bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("Invalid step %q", part)
+			}
+		}
+		lo, hi := min, max
+		if rangePart != "*" {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(loPart); err != nil {
+				return nil, fmt.Errorf("Invalid value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiPart); err != nil {
+					return nil, fmt.Errorf("Invalid range %q", part)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("Value out of range %q (%d-%d)", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func parseCronSchedule(expr string) (cronSchedule, error) {
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("Invalid cron expression %q: expected 5 fields", expr)
+	}
+	var s cronSchedule
+	var err error
+	bounds := []struct {
+		dst      *[]bool
+		min, max int
+	}{{&s.minute, 0, 59}, {&s.hour, 0, 23}, {&s.dom, 1, 31}, {&s.month, 1, 12}, {&s.dow, 0, 7}}
+	for i, b := range bounds {
+		if *b.dst, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return cronSchedule{}, fmt.Errorf("Invalid cron expression %q: %w", expr, err)
+		}
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domAny, s.dowAny = fields[2] == "*", fields[4] == "*"
+	return s, nil
+}
+
+// Matches follows cron semantics: when both day-of-month and day-of-week
+// are restricted, either one matching is enough.
+func (s cronSchedule) Matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+var scheduledTasks = map[string]func() error{
+	"revalidate": refreshChannels,
+	"gc":         collectExpired,
+	"traffic":    detectTrafficAnomalies,
+	"watchdog":   checkResources,
+	"verify":     sweepReleases,
+}
+
+func collectExpired() error {
+	manifestCache.Purge()
+	negativeCache.Purge()
+	librariesCache.Purge()
+	releaseNotesCache.Purge()
+	return nil
+}
+
+type scheduledTask struct {
+	name     string
+	run      func() error
+	schedule cronSchedule
+	// every runs the task on a fixed interval instead of the schedule, for
+	// intervals finer than cron's minutes.
+	every time.Duration
+	next  time.Time
+}
+
+// newScheduler validates the configured schedules; an empty expression
+// disables a task. A refresh interval adds the channel refresh as an
+// interval task.
+func newScheduler(schedules map[string]string, refresh time.Duration) ([]scheduledTask, error) {
+	var tasks []scheduledTask
+	if refresh > 0 {
+		tasks = append(tasks, scheduledTask{name: "refresh", run: refreshChannels, every: refresh})
+	}
+	for name, expr := range schedules {
+		if expr == "" {
+			continue
+		}
+		run, ok := scheduledTasks[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown scheduled task %q", name)
+		}
+		schedule, err := parseCronSchedule(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Schedule for %s: %w", name, err)
+		}
+		tasks = append(tasks, scheduledTask{name: name, run: run, schedule: schedule})
+	}
+	slices.SortFunc(tasks, func(a, b scheduledTask) int { return strings.Compare(a.name, b.name) })
+	return tasks, nil
+}
+
+// runScheduler wakes at the start of every minute, and whenever an interval
+// task is due, and queues the tasks whose time has come on the job pool.
+func runScheduler(tasks []scheduledTask) {
+	if len(tasks) == 0 {
+		return
+	}
+	for i := range tasks {
+		tasks[i].next = clock.Now().Add(tasks[i].every)
+	}
+	for {
+		now := clock.Now()
+		minute := now.Truncate(time.Minute).Add(time.Minute)
+		wake := minute
+		for _, task := range tasks {
+			if task.every > 0 && task.next.Before(wake) {
+				wake = task.next
+			}
+		}
+		time.Sleep(wake.Sub(now))
+		for i := range tasks {
+			task := &tasks[i]
+			if task.every > 0 {
+				if !task.next.After(wake) {
+					jobs.Submit("scheduled:"+task.name, task.run)
+					task.next = wake.Add(task.every)
+				}
+			} else if wake.Equal(minute) && task.schedule.Matches(minute) {
+				jobs.Submit("scheduled:"+task.name, task.run)
+			}
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Selene Update Server</title>
+<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse}td,th{padding:.3em 1em;border-bottom:1px solid #ccc;text-align:left}</style>
+</head>
+<body>
+<h1>Selene Update Server</h1>
+<p>Signed in as {{.Role}}{{if .Sso}} · <a href="/admin/logout">Sign out</a>{{end}}{{if not .Status.Ready}} · <strong>warming up</strong>{{end}}</p>
+<h2>Channels</h2>
+<table>
+<tr><th>Channel</th><th>Version</th><th>Pinned</th><th>Cached</th><th>Requests</th></tr>
+{{range .Channels}}<tr><td>{{.Key}}</td><td>{{.Status.Version}}</td><td>{{.Status.Pinned}}</td><td>{{.Status.Cached}}</td><td>{{.Status.Requests}}</td></tr>
+{{end}}</table>
+{{if .Status.Blocked}}<h2>Blocked versions</h2>
+<ul>{{range .Status.Blocked}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Status.RecentErrors}}<h2>Recent errors</h2>
+<table>
+<tr><th>Time</th><th>Channel</th><th>Error</th></tr>
+{{range .Status.RecentErrors}}<tr><td>{{.Time.Format "2006-01-02 15:04:05"}}</td><td>{{.Channel}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+type dashboardChannel struct {
+	Key    string
+	Status adminChannelStatus
+}
+
+func adminDashboard(w http.ResponseWriter, role string) {
+	status := buildAdminStatus()
+	channels := make([]dashboardChannel, 0, len(status.Channels))
+	for key, s := range status.Channels {
+		channels = append(channels, dashboardChannel{Key: key, Status: s})
+	}
+	slices.SortFunc(channels, func(a, b dashboardChannel) int { return strings.Compare(a.Key, b.Key) })
+	w.Header().Set("Content-Type", htmlContentType)
+	err := dashboardTemplate.Execute(w, struct {
+		Role     string
+		Sso      bool
+		Status   adminStatus
+		Channels []dashboardChannel
+	}{role, oidc != nil, status, channels})
+	if err != nil {
+		log.Printf("Warning: failed to render admin dashboard: %v", err)
+	}
+}
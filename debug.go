@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type upstreamTiming struct {
+	Call         string  `json:"call"`
+	Milliseconds float64 `json:"milliseconds"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// resolveTrace collects what the resolver did for one request. All methods
+// accept a nil receiver so the resolver can record unconditionally.
+type resolveTrace struct {
+	ManifestCache string           `json:"manifestCache"`
+	Pinned        string           `json:"pinned,omitempty"`
+	Upstream      []upstreamTiming `json:"upstream"`
+	Decisions     []string         `json:"decisions"`
+	Stale         bool             `json:"stale"`
+	Milliseconds  float64          `json:"milliseconds"`
+}
+
+func (t *resolveTrace) Decide(format string, args ...any) {
+	if t != nil {
+		t.Decisions = append(t.Decisions, fmt.Sprintf(format, args...))
+	}
+}
+
+func (t *resolveTrace) Call(call string, started time.Time, err error) {
+	if t == nil {
+		return
+	}
+	timing := upstreamTiming{Call: call, Milliseconds: float64(time.Since(started).Microseconds()) / 1000}
+	if err != nil {
+		timing.Error = err.Error()
+	}
+	t.Upstream = append(t.Upstream, timing)
+}
+
+type debugManifest struct {
+	UpdaterResponse
+	Debug *resolveTrace `json:"debug"`
+}
+
+// debugAllowed gates ?debug=1: either debug output is enabled for everyone,
+// or the request carries the configured debug token.
+func debugAllowed(r *http.Request) bool {
+	if r.URL.Query().Get("debug") != "1" {
+		return false
+	}
+	if config().DebugResponses {
+		return true
+	}
+	token := r.Header.Get("X-Debug-Token")
+	return config().DebugToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(config().DebugToken)) == 1
+}
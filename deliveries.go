@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	maxDeliveryAttempts = 5
+	deliveryBackoff     = 5 * time.Second
+	maxDeadLetters      = 500
+)
+
+// notification is the serializable form of an event handed to notifiers, so
+// that undeliverable ones can be persisted and redelivered later.
+type notification struct {
+	Type            EventType        `json:"type"`
+	Channel         string           `json:"channel"`
+	Version         string           `json:"version,omitempty"`
+	PreviousVersion string           `json:"previousVersion,omitempty"`
+	Manifest        *UpdaterResponse `json:"manifest,omitempty"`
+	Error           string           `json:"error,omitempty"`
+	Reason          string           `json:"reason,omitempty"`
+}
+
+func deliver(n Notifier, msg notification) error {
+	switch msg.Type {
+	case EventReleaseDetected:
+		return n.OnNewRelease(msg.Channel, *msg.Manifest, msg.PreviousVersion)
+	case EventResolutionFailed:
+		return n.OnResolutionFailure(msg.Channel, fmt.Errorf("%s", msg.Error))
+	case EventRolloutHalted:
+		return n.OnRolloutHalted(msg.Channel, msg.Version, msg.Reason)
+	case EventTrafficAnomaly:
+		return n.OnTrafficAnomaly(msg.Channel, msg.Reason)
+	case EventResourceAlert:
+		return n.OnResourceAlert(msg.Reason)
+	case EventSizeRegression:
+		return n.OnSizeRegression(msg.Channel, msg.Version, msg.Reason)
+	case EventDependencyChanged:
+		return n.OnDependencyChanged(msg.Channel, msg.Version, msg.Reason)
+	default:
+		return fmt.Errorf("Unknown notification type %q", msg.Type)
+	}
+}
+
+// deliveryBackoffFor returns the delay before retrying after the given
+// attempt, doubling each time.
+func deliveryBackoffFor(attempt int) time.Duration {
+	return deliveryBackoff << (attempt - 1)
+}
+
+type deadLetter struct {
+	Id           string       `json:"id"`
+	Notifier     string       `json:"notifier"`
+	Notification notification `json:"notification"`
+	Attempts     int          `json:"attempts"`
+	LastError    string       `json:"lastError"`
+	FailedAt     time.Time    `json:"failedAt"`
+}
+
+type deadLetterStore struct {
+	mu      sync.Mutex
+	path    string
+	letters []deadLetter
+}
+
+func newDeadLetterStore(path string) *deadLetterStore {
+	return &deadLetterStore{path: path}
+}
+
+func (s *deadLetterStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.letters)
+}
+
+func (s *deadLetterStore) save() {
+	if s.path == "" {
+		return
+	}
+	if err := writeJsonFile(s.path, s.letters); err != nil {
+		log.Printf("Warning: failed to persist dead letters: %v", err)
+	}
+}
+
+func (s *deadLetterStore) Add(letter deadLetter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, letter)
+	if len(s.letters) > maxDeadLetters {
+		s.letters = s.letters[len(s.letters)-maxDeadLetters:]
+	}
+	s.save()
+}
+
+// Take removes and returns the dead letter with the given id.
+func (s *deadLetterStore) Take(id string) (deadLetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.letters, func(l deadLetter) bool { return l.Id == id })
+	if i < 0 {
+		return deadLetter{}, false
+	}
+	letter := s.letters[i]
+	s.letters = slices.Delete(s.letters, i, i+1)
+	s.save()
+	return letter, true
+}
+
+func (s *deadLetterStore) List() []deadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]deadLetter{}, s.letters...)
+}
+
+type notifierDeliveryStatus struct {
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	Delivered       int64     `json:"delivered"`
+	Retried         int64     `json:"retried"`
+	DeadLettered    int64     `json:"deadLettered"`
+	LastDeliveredAt time.Time `json:"lastDeliveredAt,omitzero"`
+	LastError       string    `json:"lastError,omitempty"`
+	LastErrorAt     time.Time `json:"lastErrorAt,omitzero"`
+}
+
+type deliveryStatus struct {
+	Notifiers   []notifierDeliveryStatus `json:"notifiers"`
+	DeadLetters []deadLetter             `json:"deadLetters"`
+}
+
+func newDeadLetterId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
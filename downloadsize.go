@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+type DownloadSizeConfig struct {
+	// MaxGrowthPercent alerts when a release's total download is that much
+	// larger than the one it replaces. 0 only tracks sizes.
+	MaxGrowthPercent int `json:"maxGrowthPercent"`
+}
+
+// downloadSizes remembers the total download size of each version, keyed by
+// "group:artifact:version", so a regression shows up before users download
+// a release that accidentally bundles debug symbols or duplicate natives.
+var downloadSizes = struct {
+	sync.RWMutex
+	sizes map[string]int64
+}{sizes: make(map[string]int64)}
+
+func knownDownloadSize(coordinates, version string) (int64, bool) {
+	downloadSizes.RLock()
+	defer downloadSizes.RUnlock()
+	size, ok := downloadSizes.sizes[coordinates+":"+version]
+	return size, ok
+}
+
+// downloadSize sums the sizes the repository reports for a manifest's jar
+// and libraries.
+func downloadSize(coordinates string, manifest UpdaterResponse) (int64, error) {
+	if size, ok := knownDownloadSize(coordinates, manifest.Version); ok {
+		return size, nil
+	}
+	size, err := contentLength(manifest.Url)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", manifest.Url, err)
+	}
+	for _, url := range manifest.Libraries {
+		length, err := contentLength(url)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", url, err)
+		}
+		size += length
+	}
+	downloadSizes.Lock()
+	downloadSizes.sizes[coordinates+":"+manifest.Version] = size
+	downloadSizes.Unlock()
+	return size, nil
+}
+
+func contentLength(url string) (int64, error) {
+	resp, err := downloadDo(bundleClient, http.MethodHead, url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HEAD returned %s", resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return 0, fmt.Errorf("No Content-Length")
+	}
+	return resp.ContentLength, nil
+}
+
+// checkDownloadSize compares a new release's download size with the
+// version it replaces on the channel.
+func checkDownloadSize(key string, manifest UpdaterResponse, previousVersion string) error {
+	ch, err := parseChannelKey(key)
+	if err != nil {
+		return err
+	}
+	coordinates := ch.Group + ":" + ch.Artifact
+	if _, err := downloadSize(coordinates, manifest); err != nil {
+		return err
+	}
+	if config().DownloadSize.MaxGrowthPercent <= 0 || previousVersion == "" {
+		return nil
+	}
+	previous, err := versionManifests.Do(key+"@"+previousVersion, versionManifestTTL, func() (UpdaterResponse, error) {
+		return releaseManifest(ch, previousVersion)
+	})
+	if err != nil {
+		return err
+	}
+	reason, err := downloadGrowth(coordinates, previous, manifest)
+	if err != nil || reason == "" {
+		return err
+	}
+	log.Printf("Warning: %s %s", key, reason)
+	events.Publish(Event{Type: EventSizeRegression, Channel: key, Version: manifest.Version, PreviousVersion: previousVersion, Reason: reason})
+	return nil
+}
+
+// downloadGrowth describes how much next's download grew over previous's
+// when that exceeds downloadSize.maxGrowthPercent, or returns "".
+func downloadGrowth(coordinates string, previous, next UpdaterResponse) (string, error) {
+	growth := config().DownloadSize.MaxGrowthPercent
+	if growth <= 0 {
+		return "", nil
+	}
+	size, err := downloadSize(coordinates, next)
+	if err != nil {
+		return "", err
+	}
+	previousSize, err := downloadSize(coordinates, previous)
+	if err != nil || previousSize == 0 {
+		return "", err
+	}
+	if change := (size - previousSize) * 100 / previousSize; change > int64(growth) {
+		return fmt.Sprintf("download grew %d%% from %s (%s) to %s (%s)", change, formatBytes(previousSize), previous.Version, formatBytes(size), next.Version), nil
+	}
+	return "", nil
+}
+
+func subscribeDownloadSizes(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type != EventReleaseDetected || e.Manifest == nil {
+			return
+		}
+		manifest := *e.Manifest
+		jobs.Submit("download-size", func() error {
+			return checkDownloadSize(e.Channel, manifest, e.PreviousVersion)
+		})
+	})
+}
+
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
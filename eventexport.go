@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventExportConfig publishes release and telemetry events to a message
+// queue. Type is "nats" (core NATS protocol) or "kafka-rest" (a Kafka REST
+// Proxy in front of the cluster).
+type EventExportConfig struct {
+	Type   string   `json:"type" required:"true"`
+	Url    string   `json:"url" required:"true"`
+	Topic  string   `json:"topic,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+var defaultExportedEvents = []string{
+	string(EventReleaseDetected),
+	string(EventManifestUpdated),
+	string(EventReleaseYanked),
+	string(EventRolloutHalted),
+	string(EventTrafficAnomaly),
+	string(EventResourceAlert),
+	string(EventSizeRegression),
+}
+
+type exportedEvent struct {
+	Type            EventType        `json:"type"`
+	Channel         string           `json:"channel"`
+	Version         string           `json:"version,omitempty"`
+	PreviousVersion string           `json:"previousVersion,omitempty"`
+	Reason          string           `json:"reason,omitempty"`
+	Error           string           `json:"error,omitempty"`
+	Manifest        *UpdaterResponse `json:"manifest,omitempty"`
+	Time            time.Time        `json:"time"`
+}
+
+type eventSink interface {
+	Publish(eventType, key string, payload []byte) error
+}
+
+type eventExporter struct {
+	events []string
+	sink   eventSink
+	queue  chan exportedEvent
+}
+
+func newEventExporter(cfg EventExportConfig) (*eventExporter, error) {
+	x := &eventExporter{events: cfg.Events, queue: make(chan exportedEvent, 256)}
+	if len(x.events) == 0 {
+		x.events = defaultExportedEvents
+	}
+	switch cfg.Type {
+	case "nats":
+		if cfg.Topic == "" {
+			cfg.Topic = "selene.updates"
+		}
+		x.sink = &natsSink{url: cfg.Url, subject: cfg.Topic}
+	case "kafka-rest":
+		if cfg.Topic == "" {
+			cfg.Topic = "selene-updates"
+		}
+		x.sink = &kafkaRestSink{url: strings.TrimSuffix(cfg.Url, "/"), topic: cfg.Topic}
+	default:
+		return nil, fmt.Errorf("Unknown event export type %q", cfg.Type)
+	}
+	return x, nil
+}
+
+func (x *eventExporter) Subscribe(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if !slices.Contains(x.events, string(e.Type)) || slices.Contains(config().PrivateChannels, e.Channel) {
+			return
+		}
+		out := exportedEvent{Type: e.Type, Channel: e.Channel, Version: e.Version, PreviousVersion: e.PreviousVersion, Reason: e.Reason, Manifest: e.Manifest, Time: e.Time}
+		if e.Err != nil {
+			out.Error = e.Err.Error()
+		}
+		select {
+		case x.queue <- out:
+		default:
+			log.Printf("Warning: event export queue full, dropping %s for %s", e.Type, e.Channel)
+		}
+	})
+}
+
+func (x *eventExporter) Run() {
+	for e := range x.queue {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			log.Printf("Warning: failed to encode %s event: %v", e.Type, err)
+			continue
+		}
+		if err := x.sink.Publish(string(e.Type), e.Channel, payload); err != nil {
+			log.Printf("Warning: failed to export %s event for %s: %v", e.Type, e.Channel, err)
+		}
+	}
+}
+
+// natsSink speaks just enough of the NATS client protocol to publish: it
+// connects lazily, answers server PINGs and reconnects after errors.
+type natsSink struct {
+	url     string
+	subject string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (s *natsSink) connect() (net.Conn, error) {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	conn, err := net.DialTimeout("tcp", host, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("Unexpected NATS greeting %q", strings.TrimSpace(info))
+	}
+	conn.SetReadDeadline(time.Time{})
+	options := map[string]any{"verbose": false, "pedantic": false, "name": "selene-update-server"}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options["user"], options["pass"] = u.User.Username(), password
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go s.readLoop(conn, reader)
+	return conn, nil
+}
+
+func (s *natsSink) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			s.drop(conn)
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			s.mu.Lock()
+			conn.Write([]byte("PONG\r\n"))
+			s.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Printf("Warning: NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (s *natsSink) drop(conn net.Conn) {
+	conn.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+}
+
+// Publish sends to <subject>.<event type> so subscribers can filter with
+// wildcards; NATS has no message key, so the channel is only in the payload.
+func (s *natsSink) Publish(eventType, key string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		conn, err := s.connect()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "PUB %s.%s %d\r\n", s.subject, eventType, len(payload))
+	msg.Write(payload)
+	msg.WriteString("\r\n")
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := s.conn.Write(msg.Bytes()); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+type kafkaRestSink struct {
+	url   string
+	topic string
+}
+
+// Publish keys records by channel so per-channel ordering is preserved
+// within a partition.
+func (s *kafkaRestSink) Publish(eventType, key string, payload []byte) error {
+	body, err := json.Marshal(map[string]any{
+		"records": []map[string]any{{"key": key, "value": json.RawMessage(payload)}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(s.url+"/topics/"+url.PathEscape(s.topic), "application/vnd.kafka.json.v2+json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Kafka REST proxy returned %s", resp.Status)
+	}
+	return nil
+}
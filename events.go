@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventReleaseDetected  EventType = "release_detected"
+	EventManifestUpdated  EventType = "manifest_updated"
+	EventReleaseYanked    EventType = "release_yanked"
+	EventConfigReloaded   EventType = "config_reloaded"
+	EventResolutionFailed EventType = "resolution_failed"
+	EventRolloutHalted    EventType = "rollout_halted"
+	EventTrafficAnomaly   EventType = "traffic_anomaly"
+	EventResourceAlert    EventType = "resource_alert"
+	EventSizeRegression   EventType = "size_regression"
+	// EventDependencyChanged is only delivered to the mod author concerned,
+	// never published on the bus.
+	EventDependencyChanged EventType = "dependency_changed"
+)
+
+type Event struct {
+	Type            EventType
+	Channel         string
+	Version         string
+	PreviousVersion string
+	Manifest        *UpdaterResponse
+	Err             error
+	Reason          string
+	Time            time.Time
+}
+
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers []func(Event)
+}
+
+func (b *eventBus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, fn)
+}
+
+func (b *eventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = clock.Now()
+	}
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
+
+var events = &eventBus{}
+
+// releaseWatchHub fans a channel's manifest changes and yanks out to the
+// clients streaming them, over gRPC or server-sent events.
+type releaseWatchHub struct {
+	mu      sync.Mutex
+	streams map[chan Event]string
+}
+
+var releaseWatches = &releaseWatchHub{streams: map[chan Event]string{}}
+
+func (h *releaseWatchHub) Subscribe(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type != EventManifestUpdated && e.Type != EventReleaseYanked {
+			return
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for stream, key := range h.streams {
+			if key != e.Channel {
+				continue
+			}
+			select {
+			case stream <- e:
+			default:
+				log.Printf("Warning: dropped %s event for a slow watcher of %s", e.Type, key)
+			}
+		}
+	})
+}
+
+// Watch registers a stream for key until stop is called.
+func (h *releaseWatchHub) Watch(key string) (updates <-chan Event, stop func()) {
+	stream := make(chan Event, 16)
+	h.mu.Lock()
+	h.streams[stream] = key
+	h.mu.Unlock()
+	return stream, func() {
+		h.mu.Lock()
+		delete(h.streams, stream)
+		h.mu.Unlock()
+	}
+}
+
+func subscribeCacheInvalidation(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		switch e.Type {
+		case EventReleaseYanked:
+			manifestCache.Delete(e.Channel)
+		case EventConfigReloaded:
+			manifestCache.Clear()
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	outDir := fs.String("out", "public", "directory to write the static site to")
+	fs.Parse(args)
+
+	for _, ch := range allChannels() {
+		if ch.Private {
+			continue
+		}
+		dir := filepath.Join(*outDir, ch.Product, ch.Branch)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		m, err := latestResponse(exportRequest(ch), ch, platform{}, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to resolve %s: %w", ch.Key(), err)
+		}
+		resp := m.Body.(UpdaterResponse)
+		if err := writeExportJson(filepath.Join(dir, "latest.json"), resp); err != nil {
+			return err
+		}
+		if err := exportVersions(dir, ch); err != nil {
+			log.Printf("Warning: failed to export versions of %s: %v", ch.Key(), err)
+		}
+		badge := renderBadge(ch.Branch, resp.Version)
+		if err := os.WriteFile(filepath.Join(dir, "badge.svg"), badge, 0o644); err != nil {
+			return err
+		}
+
+		changelog, err := aggregateChangelog(ch, "", "")
+		if err != nil {
+			log.Printf("Warning: failed to export changelog for %s: %v", ch.Key(), err)
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, "changelog.md"), []byte(changelog), 0o644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "changelog.html"), []byte(renderMarkdown(changelog)), 0o644); err != nil {
+			return err
+		}
+		log.Printf("Exported %s (%s)", ch.Key(), resp.Version)
+	}
+	return nil
+}
+
+// exportRequest is the update check latest.json is rendered for. A static
+// host cannot negotiate, so it declares every capability but compact and
+// gets the full current manifest.
+func exportRequest(ch channel) *http.Request {
+	caps := slices.DeleteFunc(slices.Clone(supportedCapabilities), func(c string) bool { return c == capabilityCompact })
+	r := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/" + ch.Key() + "/latest.json"}, Header: make(http.Header)}
+	r.Header.Set(capabilitiesHeader, strings.Join(caps, ","))
+	return r
+}
+
+func writeExportJson(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// exportVersions writes versions.json, listing the channel's versions, and
+// the manifest of each at {version}.json like the server serves them.
+// Yanked versions are left out, as are versions that cannot name a file.
+func exportVersions(dir string, ch channel) error {
+	versions, err := channelVersions(ch)
+	if err != nil {
+		return err
+	}
+	coordinates := ch.Group + ":" + ch.Artifact
+	listing := make([]searchResult, 0, len(versions))
+	for _, version := range versions {
+		if admin.IsBlocked(coordinates, version) {
+			continue
+		}
+		if !safeFileName(version) {
+			log.Printf("Warning: skipping version %q of %s, it cannot name a file", version, ch.Key())
+			continue
+		}
+		manifest, err := releaseManifest(ch, version)
+		if err != nil {
+			log.Printf("Warning: skipping version %s of %s: %v", version, ch.Key(), err)
+			continue
+		}
+		if err := writeExportJson(filepath.Join(dir, version+".json"), manifest); err != nil {
+			return err
+		}
+		result := searchResult{Version: version, PubDate: manifest.PubDate}
+		_, result.Unavailable = linkRot.Problem(ch.Repository, coordinates, version)
+		listing = append(listing, result)
+	}
+	return writeExportJson(filepath.Join(dir, "versions.json"), listing)
+}
+
+// renderBadge draws a shields.io style "label | message" badge. Widths are
+// estimated from the text length, as the export has no font metrics.
+func renderBadge(label, message string) []byte {
+	labelWidth := 10 + 7*len(label)
+	messageWidth := 10 + 7*len(message)
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+	return fmt.Appendf(nil, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">
+<rect width="%d" height="20" fill="#555"/>
+<rect x="%d" width="%d" height="20" fill="#4c1"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%d" y="14">%s</text>
+<text x="%d" y="14">%s</text>
+</g>
+</svg>
+`, width, label, message, labelWidth, labelWidth, messageWidth, labelWidth/2, label, labelWidth+messageWidth/2, message)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestExportNewRelease exports a channel whose snapshot still holds the
+// previous release. export runs before the job pool is started, so the
+// smoke test of the new release has to happen right away.
+func TestExportNewRelease(t *testing.T) {
+	startFakeNexus(t)
+	ch, err := lookupChannel("selene-client", "stable")
+	if err != nil {
+		t.Fatalf("lookupChannel: %v", err)
+	}
+	previous, err := releaseManifest(ch, "1.1.0")
+	if err != nil {
+		t.Fatalf("releaseManifest: %v", err)
+	}
+	if _, err := lastServed.Update(ch.Key(), previous); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	t.Cleanup(func() {
+		smokeTestResults.Lock()
+		delete(smokeTestResults.results, ch.Key()+"@1.2.0")
+		smokeTestResults.Unlock()
+	})
+
+	out := t.TempDir()
+	if err := runExport([]string{"-out", out}); err != nil {
+		t.Fatalf("runExport: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "selene-client", "stable", "latest.json"))
+	if err != nil {
+		t.Fatalf("Failed to read latest.json: %v", err)
+	}
+	var exported UpdaterResponse
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Invalid latest.json: %v", err)
+	}
+	if exported.Version != "1.2.0" {
+		t.Errorf("exported version = %s, want 1.2.0", exported.Version)
+	}
+}
+
+// TestExportLatestMatchesServer checks latest.json carries what the server
+// adds to a manifest, such as the theme and the check interval.
+func TestExportLatestMatchesServer(t *testing.T) {
+	startFakeNexus(t)
+	useConfig(t, func(cfg *Config) {
+		cfg.Themes = map[string]ChannelTheme{"stable": {DisplayName: "Stable", Color: "#00aa00"}}
+		cfg.CheckInterval.Seconds = map[string]int{"stable": 300}
+	})
+	ch, err := lookupChannel("selene-client", "stable")
+	if err != nil {
+		t.Fatalf("lookupChannel: %v", err)
+	}
+
+	out := t.TempDir()
+	if err := runExport([]string{"-out", out}); err != nil {
+		t.Fatalf("runExport: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "selene-client", "stable", "latest.json"))
+	if err != nil {
+		t.Fatalf("Failed to read latest.json: %v", err)
+	}
+	var exported UpdaterResponse
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("Invalid latest.json: %v", err)
+	}
+	if exported.Theme == nil || exported.Theme.DisplayName != "Stable" {
+		t.Errorf("exported theme = %+v, want Stable", exported.Theme)
+	}
+	if exported.NextCheckAfterSeconds != 300 {
+		t.Errorf("exported nextCheckAfterSeconds = %d, want 300", exported.NextCheckAfterSeconds)
+	}
+
+	rec := serveTest(exportRequest(ch))
+	var served UpdaterResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatalf("Invalid response %d: %s", rec.Code, rec.Body)
+	}
+	if !reflect.DeepEqual(exported, served) {
+		t.Errorf("exported latest.json differs from the server's:\nexported: %+v\nserved:   %+v", exported, served)
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	for version, want := range map[string]bool{
+		"1.2.0":          true,
+		"1.2.0-SNAPSHOT": true,
+		"":               false,
+		"../1.2.0":       false,
+		"1.2/0":          false,
+		`1.2\0`:          false,
+		".hidden":        false,
+	} {
+		if got := safeFileName(version); got != want {
+			t.Errorf("safeFileName(%q) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errNoRelease marks a channel that resolved fine but has nothing publishable,
+// which is a configuration problem rather than an outage.
+var errNoRelease = errors.New("No publishable release")
+
+type failureClass string
+
+const (
+	failureNotFound failureClass = "not_found"
+	failureDisabled failureClass = "disabled"
+	failureUpstream failureClass = "upstream"
+	failureInternal failureClass = "internal"
+)
+
+const upstreamRetryAfter = 30 * time.Second
+
+var failuresByClass = expvar.NewMap("failures_by_class")
+
+type failure struct {
+	Class      failureClass
+	Status     int
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func classifyFailure(err error) failure {
+	var statusErr *nexusStatusError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, errArtifactDisabled):
+		return failure{Class: failureDisabled, Status: http.StatusForbidden, Reason: "artifact_disabled"}
+	case errors.Is(err, errArtifactNotRegistered), errors.Is(err, errChannelNotFound):
+		return failure{Class: failureNotFound, Status: http.StatusNotFound, Reason: "channel_not_found"}
+	case errors.Is(err, errNoRelease), errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		return failure{Class: failureNotFound, Status: http.StatusNotFound, Reason: "release_not_found"}
+	case errors.Is(err, errUpstreamBudgetExceeded):
+		return failure{Class: failureUpstream, Status: http.StatusServiceUnavailable, Reason: "upstream_rate_limited",
+			RetryAfter: max(upstreamBudget.RetryAfter(), time.Second)}
+	case errors.As(err, &netErr):
+		return failure{Class: failureUpstream, Status: http.StatusServiceUnavailable, Reason: "upstream_unreachable", RetryAfter: upstreamRetryAfter}
+	case errors.As(err, &statusErr):
+		return failure{Class: failureUpstream, Status: http.StatusBadGateway, Reason: "upstream_error", RetryAfter: upstreamRetryAfter}
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return failure{Class: failureUpstream, Status: http.StatusBadGateway, Reason: "upstream_invalid_response", RetryAfter: upstreamRetryAfter}
+	}
+	return failure{Class: failureInternal, Status: http.StatusInternalServerError, Reason: "internal_error"}
+}
+
+type failureResponse struct {
+	Error             string        `json:"error"`
+	Reason            string        `json:"reason"`
+	RetryAfterSeconds int           `json:"retryAfterSeconds,omitempty"`
+	Debug             *resolveTrace `json:"debug,omitempty"`
+}
+
+// writeFailure maps err to 403/404 for channel misconfiguration, 502/503 for
+// upstream problems and 500 for everything else. Upstream failures carry a
+// JSON body with a reason code and retry hint so launchers can back off.
+func writeFailure(w http.ResponseWriter, message string, err error) {
+	writeTracedFailure(w, message, err, nil)
+}
+
+// writeTracedFailure is writeFailure with the resolver trace attached, so
+// ?debug=1 also explains failed update checks.
+func writeTracedFailure(w http.ResponseWriter, message string, err error, trace *resolveTrace) {
+	f := classifyFailure(err)
+	failuresByClass.Add(string(f.Class), 1)
+	switch f.Class {
+	case failureUpstream:
+		log.Printf("Warning: %s: %v", message, err)
+		seconds := int(math.Ceil(f.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeJsonResponseStatus(w, f.Status, "error", failureResponse{Error: message, Reason: f.Reason, RetryAfterSeconds: seconds, Debug: trace})
+		return
+	case failureInternal:
+		log.Printf("Error: %s: %v", message, err)
+	}
+	if trace != nil {
+		writeJsonResponseStatus(w, f.Status, "error", failureResponse{Error: err.Error(), Reason: f.Reason, Debug: trace})
+		return
+	}
+	if message == "" || f.Class == failureNotFound || f.Class == failureDisabled {
+		message = http.StatusText(f.Status)
+	}
+	http.Error(w, message, f.Status)
+}
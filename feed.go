@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	maxFeedEntries  = 20
+	atomContentType = "application/atom+xml; charset=utf-8"
+)
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Id      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Links   []atomLink   `xml:"link"`
+	Content *atomContent `xml:"content,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// feeds keeps rendered feeds, appcasts and update4j configurations, keyed
+// by channel and file name, as long as a manifest, since a new release
+// changes them.
+var feeds = newMemoizer[string, []byte]("feed", 64)
+
+func feedHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	data, err := feeds.Do(ch.Key()+"/feed.xml", manifestCacheTTL, func() ([]byte, error) {
+		return renderFeed(ch)
+	})
+	if err != nil {
+		writeFailure(w, "Failed to build feed", err)
+		return
+	}
+	writeBody(w, atomContentType, data)
+}
+
+// recentManifests resolves the manifests of the newest versions that are
+// not yanked, skipping any that fail to resolve.
+func recentManifests(ch channel, versions []string, purpose string) []UpdaterResponse {
+	versions = slices.DeleteFunc(slices.Clone(versions), func(version string) bool {
+		return admin.IsBlocked(ch.Group+":"+ch.Artifact, version)
+	})
+	slices.SortFunc(versions, func(a, b string) int {
+		return compareVersions(b, a)
+	})
+	var manifests []UpdaterResponse
+	for _, version := range versions[:min(len(versions), maxFeedEntries)] {
+		manifest, err := versionManifests.Do(ch.Key()+"@"+version, versionManifestTTL, func() (UpdaterResponse, error) {
+			return releaseManifest(ch, version)
+		})
+		if err != nil {
+			log.Printf("Warning: failed to add %s %s to %s: %v", ch.Key(), version, purpose, err)
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests
+}
+
+// releaseNotesHtml renders a version's release notes, or returns "" when
+// none are published.
+func releaseNotesHtml(ch channel, manifest UpdaterResponse) string {
+	url, ok := manifest.Assets[roleNotes]
+	if !ok {
+		return ""
+	}
+	notes, err := fetchReleaseNotes(ch.Group+":"+ch.Artifact+":"+manifest.Version, url)
+	if err != nil {
+		log.Printf("Warning: failed to fetch release notes for %s: %v", manifest.Version, err)
+		return ""
+	}
+	return renderMarkdown(notes)
+}
+
+func channelTitle(ch channel) string {
+	if theme := channelTheme(ch); theme != nil && theme.DisplayName != "" {
+		return theme.DisplayName
+	}
+	return ch.Key()
+}
+
+// renderFeed builds an Atom feed of the channel's most recent versions that
+// are not yanked, with their release notes when published.
+func renderFeed(ch channel) ([]byte, error) {
+	versions, err := channelVersions(ch)
+	if err != nil {
+		return nil, err
+	}
+	title := channelTitle(ch)
+	base := "/" + ch.Key() + "/"
+	feed := atomFeed{
+		Id:     "urn:selene:" + ch.Product + ":" + ch.Branch,
+		Title:  title,
+		Author: atomAuthor{Name: "Selene"},
+		Links:  []atomLink{{Href: base + "feed.xml", Rel: "self", Type: "application/atom+xml"}},
+	}
+	for _, manifest := range recentManifests(ch, versions, "feed") {
+		entry := atomEntry{
+			Id:      feed.Id + ":" + manifest.Version,
+			Title:   ch.Product + " " + manifest.Version,
+			Updated: manifest.PubDate,
+			Links: []atomLink{
+				{Href: base + manifest.Version + ".json", Rel: "alternate", Type: "application/json"},
+				{Href: manifest.Url, Rel: "enclosure", Type: "application/java-archive"},
+			},
+		}
+		if entry.Updated == "" {
+			entry.Updated = clock.Now().UTC().Format(time.RFC3339)
+		}
+		if notes := releaseNotesHtml(ch, manifest); notes != "" {
+			entry.Content = &atomContent{Type: "html", Body: notes}
+		}
+		feed.Entries = append(feed.Entries, entry)
+		feed.Updated = max(feed.Updated, entry.Updated)
+	}
+	if feed.Updated == "" {
+		feed.Updated = clock.Now().UTC().Format(time.RFC3339)
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FilesystemConfig replaces Nexus with releases imported from offline
+// bundles, for update servers without any connection to a repository. Each
+// release is unpacked to <path>/<product>/<branch>/<version>/ and its files
+// are served from /files/ under PublicUrl. With TrustedKeys set, only bundles
+// signed by one of these base64 Ed25519 public keys are accepted.
+type FilesystemConfig struct {
+	Path        string   `json:"path" required:"true"`
+	PublicUrl   string   `json:"publicUrl" required:"true"`
+	TrustedKeys []string `json:"trustedKeys,omitempty"`
+}
+
+const maxImportBytes = 2 << 30
+
+var (
+	errReleaseExists      = errors.New("Release already imported")
+	errInvalidBundle      = errors.New("Invalid bundle")
+	errReleaseNotImported = fmt.Errorf("%w: no release imported for channel", errNoRelease)
+)
+
+type filesystemBackend struct {
+	cfg     FilesystemConfig
+	trusted []ed25519.PublicKey
+}
+
+// releaseStore is nil unless the filesystem backend is configured.
+var releaseStore *filesystemBackend
+
+func newFilesystemBackend(cfg FilesystemConfig) (*filesystemBackend, error) {
+	b := &filesystemBackend{cfg: cfg}
+	for _, key := range cfg.TrustedKeys {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("Trusted key %q is not a base64 encoded Ed25519 public key", key)
+		}
+		b.trusted = append(b.trusted, raw)
+	}
+	if err := os.MkdirAll(cfg.Path, 0o755); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// safeFileName reports whether a version can name a file or directory as
+// is: without path separators and not starting with a dot, so it stays
+// where it is put and is not hidden.
+func safeFileName(version string) bool {
+	return version != "" && !strings.ContainsAny(version, "/\\") && !strings.HasPrefix(version, ".")
+}
+
+func (b *filesystemBackend) releaseDir(ch channel, version string) string {
+	return filepath.Join(b.cfg.Path, ch.Product, ch.Branch, version)
+}
+
+// Versions lists the imported versions of a channel, newest first.
+func (b *filesystemBackend) Versions(ch channel) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(b.cfg.Path, ch.Product, ch.Branch))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			versions = append(versions, entry.Name())
+		}
+	}
+	slices.SortFunc(versions, func(a, b string) int { return compareVersions(b, a) })
+	return versions, nil
+}
+
+// Resolve returns the pinned or newest imported release that is not blocked.
+func (b *filesystemBackend) Resolve(ch channel) (UpdaterResponse, error) {
+	if pinned, ok := admin.Pin(ch.Key()); ok {
+		return b.Manifest(ch, pinned)
+	}
+	versions, err := b.Versions(ch)
+	if err != nil {
+		return UpdaterResponse{}, err
+	}
+	for _, version := range versions {
+		if !admin.IsBlocked(ch.Group+":"+ch.Artifact, version) {
+			return b.Manifest(ch, version)
+		}
+	}
+	return UpdaterResponse{}, errReleaseNotImported
+}
+
+// Manifest reads an imported release and points its URLs at this server.
+func (b *filesystemBackend) Manifest(ch channel, version string) (UpdaterResponse, error) {
+	var resp UpdaterResponse
+	data, err := os.ReadFile(filepath.Join(b.releaseDir(ch, version), "manifest.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return resp, fmt.Errorf("%w: version %s not imported", errNoRelease, version)
+	} else if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, err
+	}
+	resp.Url = b.fileUrl(ch, version, resp.Url)
+	for fileName, name := range resp.Libraries {
+		resp.Libraries[fileName] = b.fileUrl(ch, version, name)
+	}
+	return resp, nil
+}
+
+// privateFileUrlTTL is how long the signed file URLs in a private channel's
+// manifest stay valid, comfortably longer than the manifest is cached.
+const privateFileUrlTTL = 24 * time.Hour
+
+// fileUrl points at a file of an imported release. Files of private channels
+// need a signature like the channel's other URLs.
+func (b *filesystemBackend) fileUrl(ch channel, version, name string) string {
+	filePath := "/files/" + ch.Product + "/" + ch.Branch + "/" + version + "/" + name
+	if ch.Private {
+		filePath, _ = signUrl(filePath, privateFileUrlTTL)
+	}
+	return strings.TrimSuffix(b.cfg.PublicUrl, "/") + filePath
+}
+
+// FileServer serves imported release files, refusing files of private
+// channels without a valid URL signature.
+func (b *filesystemBackend) FileServer() http.Handler {
+	files := http.StripPrefix("/files", http.FileServer(noListingFs{http.Dir(b.cfg.Path)}))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/files/"), "/", 3)
+		if len(segments) >= 2 && slices.Contains(config().PrivateChannels, segments[0]+"/"+segments[1]) && !validUrlSignature(r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// noListingFs hides directories from http.FileServer, so /files/ serves
+// release files without listing what else is stored next to them.
+type noListingFs struct {
+	http.FileSystem
+}
+
+func (fsys noListingFs) Open(name string) (http.File, error) {
+	f, err := fsys.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+	return f, nil
+}
+
+// Import verifies an offline bundle and unpacks it as a new release. Every
+// file must match SHA256SUMS, the manifest must only reference bundled files
+// and, with trusted keys configured, latest.json must carry a valid
+// signature by one of them.
+func (b *filesystemBackend) Import(r io.ReaderAt, size int64) (channel, UpdaterResponse, error) {
+	var ch channel
+	var manifest UpdaterResponse
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ch, manifest, fmt.Errorf("%w: %v", errInvalidBundle, err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if !fs.ValidPath(f.Name) || strings.Contains(f.Name, "\\") {
+			return ch, manifest, fmt.Errorf("%w: unsafe file name %q", errInvalidBundle, f.Name)
+		}
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s", errInvalidBundle, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	var info bundleInfo
+	data, err := read("bundle.json")
+	if err != nil {
+		return ch, manifest, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return ch, manifest, fmt.Errorf("%w: bundle.json: %v", errInvalidBundle, err)
+	}
+	if ch, err = lookupChannel(info.Product, info.Branch); err != nil {
+		return ch, manifest, err
+	}
+	if !safeFileName(info.Version) {
+		return ch, manifest, fmt.Errorf("%w: invalid version %q", errInvalidBundle, info.Version)
+	}
+
+	sums, err := b.verifySums(files, read)
+	if err != nil {
+		return ch, manifest, err
+	}
+	manifestData, err := read("latest.json")
+	if err != nil {
+		return ch, manifest, err
+	}
+	if err := b.verifySignature(info, manifestData, read); err != nil {
+		return ch, manifest, err
+	}
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return ch, manifest, fmt.Errorf("%w: latest.json: %v", errInvalidBundle, err)
+	}
+	if manifest.Version != info.Version {
+		return ch, manifest, fmt.Errorf("%w: latest.json is for version %s, bundle for %s", errInvalidBundle, manifest.Version, info.Version)
+	}
+	stored := manifest
+	stored.Url = "files/" + path.Base(manifest.FileName)
+	if err := checkBundled(sums, stored.Url, manifest.Sha256); err != nil {
+		return ch, manifest, err
+	}
+	stored.Libraries = make(map[string]string, len(manifest.Libraries))
+	for fileName := range manifest.Libraries {
+		name := "libraries/" + path.Base(fileName)
+		if err := checkBundled(sums, name, manifest.LibrarySha256[fileName]); err != nil {
+			return ch, manifest, err
+		}
+		stored.Libraries[fileName] = name
+	}
+
+	dir := b.releaseDir(ch, info.Version)
+	if _, err := os.Stat(dir); err == nil {
+		return ch, manifest, errReleaseExists
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return ch, manifest, err
+	}
+	// Unpack next to the final directory and rename it into place, so a
+	// release is never visible half written.
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+info.Version+".import")
+	if err != nil {
+		return ch, manifest, err
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		return ch, manifest, err
+	}
+	for name, f := range files {
+		if err := extractFile(f, filepath.Join(tmp, filepath.FromSlash(name))); err != nil {
+			return ch, manifest, err
+		}
+	}
+	storedData, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return ch, manifest, err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "manifest.json"), storedData, 0o644); err != nil {
+		return ch, manifest, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return ch, manifest, err
+	}
+	return ch, manifest, nil
+}
+
+// verifySums checks every bundled file against SHA256SUMS and returns the
+// checksums by file name.
+func (b *filesystemBackend) verifySums(files map[string]*zip.File, read func(string) ([]byte, error)) (map[string]string, error) {
+	data, err := read("SHA256SUMS")
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed SHA256SUMS line %q", errInvalidBundle, scanner.Text())
+		}
+		sums[name] = sum
+	}
+	for name, f := range files {
+		expected, ok := sums[name]
+		if !ok {
+			if name == "SHA256SUMS" || name == "bundle.json" || name == "latest.json.sig" {
+				continue
+			}
+			return nil, fmt.Errorf("%w: %s is not listed in SHA256SUMS", errInvalidBundle, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != expected {
+			return nil, fmt.Errorf("%w: checksum mismatch for %s", errInvalidBundle, name)
+		}
+	}
+	for name := range sums {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("%w: %s is listed in SHA256SUMS but missing", errInvalidBundle, name)
+		}
+	}
+	return sums, nil
+}
+
+func (b *filesystemBackend) verifySignature(info bundleInfo, manifestData []byte, read func(string) ([]byte, error)) error {
+	sigData, err := read("latest.json.sig")
+	if err != nil {
+		if len(b.trusted) > 0 {
+			return fmt.Errorf("%w: bundle is not signed", errInvalidBundle)
+		}
+		return nil
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return fmt.Errorf("%w: malformed latest.json.sig", errInvalidBundle)
+	}
+	keys := b.trusted
+	if len(keys) == 0 {
+		// Without trusted keys the embedded key only guards against
+		// corruption, not against a forged bundle.
+		raw, err := base64.StdEncoding.DecodeString(info.PublicKey)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: signed bundle without a valid public key", errInvalidBundle)
+		}
+		keys = []ed25519.PublicKey{raw}
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, manifestData, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: latest.json is not signed by a trusted key", errInvalidBundle)
+}
+
+// importBundle handles POST /admin/import with a bundle zip as the body.
+func importBundle(w http.ResponseWriter, r *http.Request) {
+	if releaseStore == nil {
+		http.Error(w, "Filesystem backend not configured", http.StatusNotFound)
+		return
+	}
+	tmp, err := os.CreateTemp("", "selene-import-*.zip")
+	if err != nil {
+		writeFailure(w, "Failed to import bundle", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	ch, manifest, err := releaseStore.Import(tmp, size)
+	switch {
+	case errors.Is(err, errInvalidBundle):
+		log.Printf("Warning: rejected bundle import: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, errReleaseExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		writeFailure(w, "Failed to import bundle", err)
+		return
+	}
+	log.Printf("Imported %s %s", ch.Key(), manifest.Version)
+	manifestCache.Delete(ch.Key())
+	negativeCache.Clear()
+	current, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to resolve channel after import", err)
+		return
+	}
+	writeAdminJson(w, map[string]any{"channel": ch.Key(), "imported": manifest.Version, "current": current.Version})
+}
+
+func checkBundled(sums map[string]string, name, expected string) error {
+	sum, ok := sums[name]
+	if !ok {
+		return fmt.Errorf("%w: manifest references %s, which is not bundled", errInvalidBundle, name)
+	}
+	if expected != "" && sum != expected {
+		return fmt.Errorf("%w: %s does not match the manifest checksum", errInvalidBundle, name)
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, rc)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// getdown reads getdown.txt and digest2.txt from its appbase,
+// /{product}/{branch}/getdown/, and then every file they list relative to
+// it, which is redirected to the repository.
+
+// getdownOs maps platform rule OS names to getdown's platform qualifiers.
+var getdownOs = map[string]string{
+	"windows": "windows",
+	"macos":   "mac os x",
+	"linux":   "linux",
+}
+
+var getdownSha256 = newNamedLruCache[string, string]("getdownDigests", 1024)
+
+func requestBaseUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func getdownHandler(w http.ResponseWriter, r *http.Request, ch channel, file string) {
+	artifact, err := artifacts.Lookup(ch.Product)
+	if err != nil {
+		writeFailure(w, "", err)
+		return
+	}
+	if artifact.MainClass == "" {
+		http.Error(w, "No mainClass configured for getdown", http.StatusNotFound)
+		return
+	}
+	recordCheckIn(ch)
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	resp = applyRollout(w.Header(), r, ch, resp)
+	markStale(w.Header(), resp)
+	switch file {
+	case "getdown.txt":
+		writeBody(w, textContentType, renderGetdownConfig(requestBaseUrl(r)+"/"+ch.Key()+"/getdown/", artifact.MainClass, ch, resp))
+	case "digest2.txt":
+		digest, err := renderGetdownDigest(requestBaseUrl(r)+"/"+ch.Key()+"/getdown/", artifact.MainClass, ch, resp)
+		if err != nil {
+			writeFailure(w, "Failed to build getdown digest", err)
+			return
+		}
+		writeBody(w, textContentType, digest)
+	default:
+		if url, ok := getdownFiles(resp)[file]; ok {
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// getdownFiles maps the paths getdown.txt lists to download URLs.
+func getdownFiles(resp UpdaterResponse) map[string]string {
+	files := map[string]string{resp.FileName: resp.Url}
+	for fileName, url := range resp.Libraries {
+		files["libraries/"+fileName] = url
+	}
+	return files
+}
+
+func renderGetdownConfig(appbase, mainClass string, ch channel, resp UpdaterResponse) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s %s\n", ch.Key(), resp.Version)
+	fmt.Fprintf(&sb, "appbase = %s\n", appbase)
+	fmt.Fprintf(&sb, "code = %s\n", resp.FileName)
+	for _, fileName := range slices.Sorted(maps.Keys(resp.Libraries)) {
+		qualifier := ""
+		for _, rule := range ch.Platforms {
+			if ok, _ := path.Match(rule.Pattern, fileName); ok {
+				qualifier = "[" + getdownOs[rule.Os] + "] "
+				break
+			}
+		}
+		fmt.Fprintf(&sb, "%scode = libraries/%s\n", qualifier, fileName)
+	}
+	fmt.Fprintf(&sb, "class = %s\n", mainClass)
+	return []byte(sb.String())
+}
+
+// renderGetdownDigest lists the SHA-256 of getdown.txt and every file,
+// followed by the digest of those lines, as getdown's digest2.txt.
+func renderGetdownDigest(appbase, mainClass string, ch channel, resp UpdaterResponse) ([]byte, error) {
+	sum := sha256.Sum256(renderGetdownConfig(appbase, mainClass, ch, resp))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "getdown.txt = %s\n", hex.EncodeToString(sum[:]))
+	files := getdownFiles(resp)
+	for _, file := range slices.Sorted(maps.Keys(files)) {
+		digest := resp.LibrarySha256[strings.TrimPrefix(file, "libraries/")]
+		if file == resp.FileName {
+			digest = resp.Sha256
+		}
+		if digest == "" {
+			var err error
+			if digest, err = fetchGetdownSha256(files[file]); err != nil {
+				return nil, fmt.Errorf("%s: %w", files[file], err)
+			}
+		}
+		fmt.Fprintf(&sb, "%s = %s\n", file, digest)
+	}
+	sum = sha256.Sum256([]byte(sb.String()))
+	fmt.Fprintf(&sb, "digest2.txt = %s\n", hex.EncodeToString(sum[:]))
+	return []byte(sb.String()), nil
+}
+
+func fetchGetdownSha256(url string) (string, error) {
+	if digest, ok := getdownSha256.Get(url); ok {
+		return digest, nil
+	}
+	resp, err := downloadDo(bundleClient, http.MethodGet, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Downloading returned %s", resp.Status)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+	getdownSha256.Set(url, digest)
+	return digest, nil
+}
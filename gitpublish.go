@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type GitPublishConfig struct {
+	RepoPath string `json:"repoPath" required:"true"`
+	Remote   string `json:"remote,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Push     bool   `json:"push,omitempty"`
+}
+
+type gitPublisher struct {
+	cfg   GitPublishConfig
+	queue chan Event
+}
+
+func newGitPublisher(cfg GitPublishConfig) *gitPublisher {
+	if cfg.Remote == "" {
+		cfg.Remote = "origin"
+	}
+	if cfg.Branch == "" {
+		cfg.Branch = "main"
+	}
+	return &gitPublisher{cfg: cfg, queue: make(chan Event, 64)}
+}
+
+func (p *gitPublisher) Subscribe(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type != EventManifestUpdated || slices.Contains(config().PrivateChannels, e.Channel) {
+			return
+		}
+		select {
+		case p.queue <- e:
+		default:
+			log.Printf("Warning: git publish queue full, dropping manifest for %s", e.Channel)
+		}
+	})
+}
+
+func (p *gitPublisher) Run() {
+	for e := range p.queue {
+		if err := p.publish(e); err != nil {
+			log.Printf("Warning: failed to publish manifest for %s to git: %v", e.Channel, err)
+		}
+	}
+}
+
+func (p *gitPublisher) git(args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", p.cfg.RepoPath}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (p *gitPublisher) publish(e Event) error {
+	relPath := filepath.Join(filepath.FromSlash(e.Channel), "latest.json")
+	path := filepath.Join(p.cfg.RepoPath, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	if err := p.git("add", relPath); err != nil {
+		return err
+	}
+	if err := p.git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	msg := fmt.Sprintf("Update %s to %s", e.Channel, e.Version)
+	if err := p.git("commit", "-m", msg); err != nil {
+		return err
+	}
+	if p.cfg.Push {
+		return p.git("push", p.cfg.Remote, "HEAD:"+p.cfg.Branch)
+	}
+	return nil
+}
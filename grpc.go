@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The Updates service of proto/updates.proto, served on grpcListen. gRPC is
+// length-prefixed protobuf over HTTP/2 with the status in trailers, so
+// net/http serves it over h2c without grpc-go or generated code.
+
+const grpcServicePath = "/selene.updater.v1.Updates/"
+
+const grpcMaxMessageSize = 1 << 20
+
+// gRPC status codes, see https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	grpcOk                 = 0
+	grpcInvalidArgument    = 3
+	grpcNotFound           = 5
+	grpcPermissionDenied   = 7
+	grpcFailedPrecondition = 9
+	grpcUnimplemented      = 12
+	grpcInternal           = 13
+	grpcUnavailable        = 14
+)
+
+type grpcError struct {
+	Code    int
+	Message string
+}
+
+func (e *grpcError) Error() string {
+	return e.Message
+}
+
+func serveGrpc(addr string, access *accessControl) {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{Addr: addr, Handler: instrument(access.Wrap(http.HandlerFunc(grpcHandler))), Protocols: &protocols}
+	log.Printf("Listening on %s, serving gRPC selene.updater.v1.Updates", addr)
+	log.Fatal(server.ListenAndServe())
+}
+
+func grpcHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	code, message := grpcOk, ""
+	if err := serveGrpcCall(w, r); err != nil {
+		code, message = grpcStatus(err)
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", url.PathEscape(message))
+	}
+}
+
+func serveGrpcCall(w http.ResponseWriter, r *http.Request) error {
+	method, _ := strings.CutPrefix(r.URL.Path, grpcServicePath)
+	if !slices.Contains([]string{"GetLatest", "ListVersions", "WatchReleases"}, method) {
+		return &grpcError{grpcUnimplemented, "Unknown method " + r.URL.Path}
+	}
+	msg, err := readGrpcMessage(r.Body)
+	if err != nil {
+		return err
+	}
+	fields, err := decodeProtoStrings(msg)
+	if err != nil {
+		return &grpcError{grpcInvalidArgument, err.Error()}
+	}
+	if replacement, ok := config().RetiredChannels[fields[1]+"/"+fields[2]]; ok {
+		message := "Channel retired"
+		if replacement != "" {
+			message += ", use " + replacement
+		}
+		return &grpcError{grpcFailedPrecondition, message}
+	}
+	ch, err := lookupChannel(fields[1], fields[2])
+	if err != nil {
+		return err
+	}
+	if ch.Private {
+		return &grpcError{grpcPermissionDenied, "Channel is private"}
+	}
+	switch method {
+	case "GetLatest":
+		resp, err := resolveChannel(ch)
+		if err != nil {
+			return err
+		}
+		return writeGrpcMessage(w, encodeManifestProto(grpcManifest(ch, resp)))
+	case "ListVersions":
+		versions, err := channelVersions(ch)
+		if err != nil {
+			return err
+		}
+		return writeGrpcMessage(w, encodeVersionListProto(versions))
+	}
+	return watchReleases(w, r, ch)
+}
+
+// grpcManifest is the manifest as internal tooling sees it: unfiltered and
+// outside any rollout.
+func grpcManifest(ch channel, resp UpdaterResponse) UpdaterResponse {
+	resp.Theme = channelTheme(ch)
+	resp.NextCheckAfterSeconds = nextCheckAfter(ch)
+	return resp
+}
+
+func watchReleases(w http.ResponseWriter, r *http.Request, ch channel) error {
+	updates, stop := releaseWatches.Watch(ch.Key())
+	defer stop()
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		return err
+	}
+	current := Event{Type: EventManifestUpdated, Channel: ch.Key(), Version: resp.Version, Manifest: &resp, Time: clock.Now()}
+	if err := writeGrpcMessage(w, encodeReleaseEventProto(ch, current)); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case e := <-updates:
+			if err := writeGrpcMessage(w, encodeReleaseEventProto(ch, e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func grpcStatus(err error) (int, string) {
+	var grpcErr *grpcError
+	if errors.As(err, &grpcErr) {
+		return grpcErr.Code, grpcErr.Message
+	}
+	f := classifyFailure(err)
+	switch f.Class {
+	case failureNotFound:
+		return grpcNotFound, err.Error()
+	case failureDisabled:
+		return grpcFailedPrecondition, err.Error()
+	case failureUpstream:
+		log.Printf("Warning: gRPC call failed: %v", err)
+		return grpcUnavailable, f.Reason
+	}
+	log.Printf("Error: gRPC call failed: %v", err)
+	return grpcInternal, "Internal error"
+}
+
+func readGrpcMessage(body io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "Missing request message"}
+	}
+	if prefix[0] != 0 {
+		return nil, &grpcError{grpcUnimplemented, "Compressed messages are not supported"}
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > grpcMaxMessageSize {
+		return nil, &grpcError{grpcInvalidArgument, "Request message too large"}
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, &grpcError{grpcInvalidArgument, "Truncated request message"}
+	}
+	return msg, nil
+}
+
+func writeGrpcMessage(w http.ResponseWriter, msg []byte) error {
+	frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+	if _, err := w.Write(append(frame, msg...)); err != nil {
+		return err
+	}
+	return http.NewResponseController(w).Flush()
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const warmupTimeout = 30 * time.Second
+
+var ready atomic.Bool
+
+func warmup(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		if mirror != nil {
+			if err := mirror.Sync(); err != nil {
+				log.Printf("Warning: initial mirror sync failed: %v", err)
+			}
+		}
+		var wg sync.WaitGroup
+		for _, ch := range allChannels() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := resolveChannel(ch); err != nil {
+					log.Printf("Warning: warmup failed for %s: %v", ch.Key(), err)
+				}
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		log.Println("Warmup complete")
+	case <-time.After(timeout):
+		log.Printf("Warning: warmup did not complete within %s", timeout)
+	}
+	ready.Store(true)
+}
+
+// upstreamHealth remembers the outcome of the most recent Nexus request so
+// readiness probes don't add upstream load while traffic is flowing.
+type upstreamHealth struct {
+	mu      sync.Mutex
+	ok      bool
+	checked time.Time
+}
+
+const upstreamHealthMaxAge = time.Minute
+
+var nexusHealth upstreamHealth
+
+func (h *upstreamHealth) Record(ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ok, h.checked = ok, time.Now()
+}
+
+// Reachable reports whether Nexus (or a mirror's primary) answered recently,
+// probing its status endpoint when no request has been made within
+// upstreamHealthMaxAge.
+func (h *upstreamHealth) Reachable() bool {
+	h.mu.Lock()
+	ok, checked := h.ok, h.checked
+	h.mu.Unlock()
+	if time.Since(checked) < upstreamHealthMaxAge {
+		return ok
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	statusUrl := config().Nexus.Url + "/service/rest/v1/status"
+	if mirror != nil {
+		statusUrl = mirror.primary.String() + "/healthz"
+	}
+	resp, err := downloadDo(client, http.MethodGet, statusUrl)
+	if errors.Is(err, errUpstreamBudgetExceeded) {
+		// Out of budget says nothing about Nexus, so the last answer stands.
+		return ok
+	}
+	ok = err == nil && resp.StatusCode == http.StatusOK
+	if err == nil {
+		resp.Body.Close()
+	}
+	h.Record(ok)
+	return ok
+}
+
+func cacheWarm() bool {
+	for _, ch := range allChannels() {
+		if _, ok := manifestCache.Get(ch.Key()); ok {
+			return true
+		}
+		if _, ok := lastServed.Get(ch.Key()); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// healthHandler only reports that the process is up and serving HTTP.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	writeBody(w, textContentType, []byte("ok"))
+}
+
+// readyHandler reports whether this instance should receive traffic: warmup
+// has finished, Nexus is reachable and at least one manifest can be served.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	var problems []string
+	if !ready.Load() {
+		problems = append(problems, "warming up")
+	} else {
+		if releaseStore == nil && !nexusHealth.Reachable() {
+			if mirror != nil {
+				problems = append(problems, "primary unreachable")
+			} else {
+				problems = append(problems, "nexus unreachable")
+			}
+		}
+		if !cacheWarm() {
+			problems = append(problems, "no manifests cached")
+		}
+	}
+	if len(problems) > 0 {
+		writeBodyStatus(w, http.StatusServiceUnavailable, textContentType, []byte(strings.Join(problems, ", ")+"\n"))
+		return
+	}
+	writeBody(w, textContentType, []byte("ok"))
+}
+
+const deepHealthTTL = 10 * time.Second
+
+type dependencyStatus struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"durationMs,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type deepHealth struct {
+	Status       string                      `json:"status"`
+	Canary       string                      `json:"canary"`
+	Version      string                      `json:"version,omitempty"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// Deep checks are shared for a few seconds so external monitors polling
+// them cannot multiply the load on Nexus.
+var deepHealthChecks = newMemoizer[string, deepHealth]("", 1)
+
+func canaryChannel() (channel, error) {
+	if config().CanaryChannel != "" {
+		return parseChannelKey(config().CanaryChannel)
+	}
+	for _, ch := range allChannels() {
+		if !ch.Private {
+			return ch, nil
+		}
+	}
+	return channel{}, errChannelNotFound
+}
+
+// syntheticResolve resolves a channel like an update check would, but
+// bypasses the manifest cache and leaves no trace in snapshots or events.
+func syntheticResolve(ch channel) (UpdaterResponse, error) {
+	switch {
+	case mirror != nil:
+		return mirror.Resolve(ch)
+	case releaseStore != nil:
+		return releaseStore.Resolve(ch)
+	}
+	var version string
+	var assets releaseAssets
+	var err error
+	if pinned, ok := admin.Pin(ch.Key()); ok {
+		version = pinned
+		assets, err = fetchVersionWithAssets(ch, pinned, nil)
+	} else {
+		version, assets, err = fetchLatestVersionWithAssets(ch, nil)
+	}
+	if err != nil {
+		return UpdaterResponse{}, err
+	}
+	return buildManifest(ch, version, assets, nil), nil
+}
+
+func checkDependency(check func() (string, error)) dependencyStatus {
+	started := time.Now()
+	detail, err := check()
+	status := dependencyStatus{Status: "ok", DurationMs: time.Since(started).Milliseconds(), Detail: detail}
+	if err != nil {
+		status.Status, status.Error = "failing", err.Error()
+	}
+	return status
+}
+
+func runDeepHealth() deepHealth {
+	report := deepHealth{Status: "ok", Dependencies: make(map[string]dependencyStatus)}
+	upstream := "nexus"
+	switch {
+	case mirror != nil:
+		upstream = "primary"
+	case releaseStore != nil:
+		upstream = "filesystem"
+	}
+	ch, err := canaryChannel()
+	if err == nil {
+		report.Canary = ch.Key()
+	}
+	report.Dependencies[upstream] = checkDependency(func() (string, error) {
+		if err != nil {
+			return "", err
+		}
+		resp, err := syntheticResolve(ch)
+		if err != nil {
+			return "", err
+		}
+		report.Version = resp.Version
+		return "resolved " + resp.Version, nil
+	})
+	report.Dependencies["cache"] = checkDependency(func() (string, error) {
+		detail := fmt.Sprintf("%d manifests cached", manifestCache.Len())
+		if config().SnapshotPath == "" {
+			return detail, nil
+		}
+		probe, err := os.CreateTemp(filepath.Dir(config().SnapshotPath), ".healthz-*")
+		if err != nil {
+			return detail, fmt.Errorf("Snapshot directory not writable: %w", err)
+		}
+		probe.Close()
+		os.Remove(probe.Name())
+		return detail, nil
+	})
+	if manifestSigningKey == nil {
+		report.Dependencies["signer"] = dependencyStatus{Status: "disabled"}
+	} else {
+		report.Dependencies["signer"] = checkDependency(func() (string, error) {
+			message := []byte("healthz " + clock.Now().String())
+			if !ed25519.Verify(manifestSigningKey.Public().(ed25519.PublicKey), message, ed25519.Sign(manifestSigningKey, message)) {
+				return "", fmt.Errorf("Signature does not verify")
+			}
+			return "ed25519", nil
+		})
+	}
+	for _, dep := range report.Dependencies {
+		if dep.Status == "failing" {
+			report.Status = "failing"
+		}
+	}
+	return report
+}
+
+// deepHealthHandler serves /healthz/deep for external monitors: a full
+// resolve of the canary channel plus a status per dependency, answering 503
+// when any of them fails.
+func deepHealthHandler(w http.ResponseWriter, r *http.Request) {
+	report, _ := deepHealthChecks.Do("", deepHealthTTL, func() (deepHealth, error) {
+		return runDeepHealth(), nil
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJsonStatus(w, status, report)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Inbound hooks from Nexus and CI are signed with a per-sender secret over
+// "<timestamp>.<nonce>.<body>". Requests outside the timestamp window or
+// reusing a nonce seen within it are rejected, so a captured call cannot be
+// replayed to flush caches or activate a release.
+const (
+	hookTimestampHeader = "X-Selene-Timestamp"
+	hookNonceHeader     = "X-Selene-Nonce"
+	hookSignatureHeader = "X-Selene-Signature"
+	hookTimestampWindow = 5 * time.Minute
+	maxHookBodyBytes    = 16 << 10
+	maxHookNonceLen     = 128
+
+	nexusSignatureHeader = "X-Nexus-Webhook-Signature"
+	nexusTimestampLayout = "2006-01-02T15:04:05.000-0700"
+)
+
+type nonceStore struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newNonceStore() *nonceStore {
+	return &nonceStore{seen: make(map[string]time.Time)}
+}
+
+// Claim records nonce until expires and reports whether it was unused.
+func (s *nonceStore) Claim(nonce string, expires time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := clock.Now()
+	for n, exp := range s.seen {
+		if now.After(exp) {
+			delete(s.seen, n)
+		}
+	}
+	if _, ok := s.seen[nonce]; ok {
+		return false
+	}
+	s.seen[nonce] = expires
+	return true
+}
+
+var hookNonces = newNonceStore()
+
+func hookSignature(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyHook checks the signature headers of a hook request and returns the
+// reason it was rejected, if any.
+func verifyHook(secret string, r *http.Request, body []byte) string {
+	timestamp := r.Header.Get(hookTimestampHeader)
+	nonce := r.Header.Get(hookNonceHeader)
+	if timestamp == "" || nonce == "" || len(nonce) > maxHookNonceLen {
+		return "Missing timestamp or nonce"
+	}
+	expected := hookSignature(secret, timestamp, nonce, body)
+	if !hmac.Equal([]byte(r.Header.Get(hookSignatureHeader)), []byte(expected)) {
+		return "Invalid signature"
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "Invalid timestamp"
+	}
+	sent := time.Unix(seconds, 0)
+	now := clock.Now()
+	if sent.Before(now.Add(-hookTimestampWindow)) || sent.After(now.Add(hookTimestampWindow)) {
+		return "Timestamp outside allowed window"
+	}
+	// A nonce only needs remembering while its timestamp is still acceptable.
+	if !hookNonces.Claim(nonce, sent.Add(hookTimestampWindow)) {
+		return "Nonce already used"
+	}
+	return ""
+}
+
+type hookRequest struct {
+	Action  string `json:"action"`
+	Channel string `json:"channel,omitempty"`
+	// Branch is accepted from senders predating channels, see legacyChannelKey.
+	Branch  string `json:"branch,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+func hookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mirror != nil {
+		http.Error(w, "Read-only mirror", http.StatusForbidden)
+		return
+	}
+	sender := strings.TrimPrefix(r.URL.Path, "/hooks/")
+	secret, ok := config().Hooks[sender]
+	if !ok || secret == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHookBodyBytes))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if signature := r.Header.Get(nexusSignatureHeader); signature != "" {
+		nexusHook(w, sender, secret, signature, body)
+		return
+	}
+	if reason := verifyHook(secret, r, body); reason != "" {
+		log.Printf("Warning: rejected hook from %s: %s", sender, reason)
+		http.Error(w, reason, http.StatusUnauthorized)
+		return
+	}
+	var req hookRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Channel = legacyChannelKey(req.Channel, req.Branch)
+	switch req.Action {
+	case "invalidate":
+		if req.Channel == "" {
+			flushCaches()
+			break
+		}
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil {
+			http.Error(w, "Unknown channel", http.StatusBadRequest)
+			return
+		}
+		manifestCache.Delete(ch.Key())
+		negativeCache.Clear()
+	case "promote":
+		ch, err := parseChannelKey(req.Channel)
+		if err != nil || req.Version == "" {
+			http.Error(w, "Missing or unknown channel or version", http.StatusBadRequest)
+			return
+		}
+		if _, slotted := releaseSlots.Current(ch.Key()); slotted {
+			http.Error(w, errChannelSlotted.Error(), http.StatusConflict)
+			return
+		}
+		if admin.IsBlocked(ch.Group+":"+ch.Artifact, req.Version) {
+			http.Error(w, "Version is yanked", http.StatusConflict)
+			return
+		}
+		err = admin.SetPin(ch.Key(), req.Version)
+		manifestCache.Delete(ch.Key())
+		if err != nil {
+			writePersistFailure(w, "pins", err)
+			return
+		}
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+	log.Printf("Hook from %s: %s %s %s", sender, req.Action, req.Channel, req.Version)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// nexusWebhook is the part of a Nexus repository component or asset webhook
+// that identifies what changed.
+type nexusWebhook struct {
+	Timestamp      string `json:"timestamp"`
+	RepositoryName string `json:"repositoryName"`
+	Action         string `json:"action"`
+	Component      *struct {
+		Group   string `json:"group"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"component"`
+	Asset *struct {
+		Name string `json:"name"`
+	} `json:"asset"`
+}
+
+func (e nexusWebhook) affects(ch channel) bool {
+	if e.RepositoryName != ch.Repository {
+		return false
+	}
+	if e.Component != nil {
+		return e.Component.Group == ch.Group && e.Component.Name == ch.Artifact
+	}
+	if e.Asset != nil {
+		prefix := strings.ReplaceAll(ch.Group, ".", "/") + "/" + ch.Artifact + "/"
+		return strings.HasPrefix(strings.TrimPrefix(e.Asset.Name, "/"), prefix)
+	}
+	return false
+}
+
+// nexusHook handles Nexus' own repository webhooks, signed with a hex
+// HMAC-SHA1 of the body. Nexus sends no nonce, so the body itself is claimed
+// instead: its timestamp makes every event unique.
+func nexusHook(w http.ResponseWriter, sender, secret, signature string, body []byte) {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+		log.Printf("Warning: rejected Nexus hook from %s: Invalid signature", sender)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var event nexusWebhook
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	now := clock.Now()
+	sent, err := time.Parse(nexusTimestampLayout, event.Timestamp)
+	if err != nil {
+		http.Error(w, "Invalid timestamp", http.StatusUnauthorized)
+		return
+	}
+	if sent.Before(now.Add(-hookTimestampWindow)) || sent.After(now.Add(hookTimestampWindow)) {
+		http.Error(w, "Timestamp outside allowed window", http.StatusUnauthorized)
+		return
+	}
+	sum := sha256.Sum256(body)
+	if !hookNonces.Claim(hex.EncodeToString(sum[:]), sent.Add(hookTimestampWindow)) {
+		http.Error(w, "Event already received", http.StatusUnauthorized)
+		return
+	}
+	var refreshed []string
+	for _, ch := range allChannels() {
+		if !event.affects(ch) {
+			continue
+		}
+		manifestCache.Delete(ch.Key())
+		jobs.Submit("hook:refresh", func() error {
+			_, err := resolveChannel(ch)
+			return err
+		})
+		refreshed = append(refreshed, ch.Key())
+	}
+	if len(refreshed) > 0 {
+		negativeCache.Clear()
+		versionManifests.Clear()
+		log.Printf("Nexus hook from %s: %s in %s, refreshing %s", sender, strings.ToLower(event.Action), event.RepositoryName, strings.Join(refreshed, ", "))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
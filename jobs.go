@@ -0,0 +1,153 @@
+package main
+
+import (
+	"expvar"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+type JobsConfig struct {
+	Workers   int `json:"workers"`
+	QueueSize int `json:"queueSize"`
+}
+
+type job struct {
+	kind   string
+	run    func() error
+	queued time.Time
+}
+
+type runningJob struct {
+	Kind    string    `json:"kind"`
+	Started time.Time `json:"started"`
+}
+
+type jobKindStats struct {
+	Completed    int64   `json:"completed"`
+	Failed       int64   `json:"failed"`
+	Rejected     int64   `json:"rejected"`
+	TotalSeconds float64 `json:"totalSeconds"`
+	WaitSeconds  float64 `json:"waitSeconds"`
+}
+
+type jobPoolStatus struct {
+	Workers  int                     `json:"workers"`
+	Queued   int                     `json:"queued"`
+	Capacity int                     `json:"capacity"`
+	Running  []runningJob            `json:"running"`
+	Kinds    map[string]jobKindStats `json:"kinds"`
+}
+
+// jobPool runs background work on a fixed number of workers. Producers
+// never block: when the queue is full the job is rejected and counted.
+type jobPool struct {
+	queue   chan job
+	workers int
+
+	mu      sync.Mutex
+	nextId  int
+	running map[int]runningJob
+	stats   map[string]*jobKindStats
+}
+
+func newJobPool(queueSize int) *jobPool {
+	return &jobPool{
+		queue:   make(chan job, queueSize),
+		running: make(map[int]runningJob),
+		stats:   make(map[string]*jobKindStats),
+	}
+}
+
+func (p *jobPool) Start(workers int) {
+	p.mu.Lock()
+	p.workers += workers
+	p.mu.Unlock()
+	for range workers {
+		go p.work()
+	}
+}
+
+// Running reports whether any workers take jobs from the queue.
+func (p *jobPool) Running() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.workers > 0
+}
+
+func (p *jobPool) kindStats(kind string) *jobKindStats {
+	s, ok := p.stats[kind]
+	if !ok {
+		s = &jobKindStats{}
+		p.stats[kind] = s
+	}
+	return s
+}
+
+func (p *jobPool) Submit(kind string, run func() error) bool {
+	select {
+	case p.queue <- job{kind: kind, run: run, queued: clock.Now()}:
+		return true
+	default:
+		p.mu.Lock()
+		p.kindStats(kind).Rejected++
+		p.mu.Unlock()
+		log.Printf("Warning: job queue full, dropping %s job", kind)
+		return false
+	}
+}
+
+func (p *jobPool) work() {
+	for j := range p.queue {
+		started := clock.Now()
+		p.mu.Lock()
+		id := p.nextId
+		p.nextId++
+		p.running[id] = runningJob{Kind: j.kind, Started: started}
+		p.mu.Unlock()
+
+		err := j.run()
+		if err != nil {
+			log.Printf("Warning: %s job failed: %v", j.kind, err)
+		}
+
+		p.mu.Lock()
+		delete(p.running, id)
+		s := p.kindStats(j.kind)
+		if err != nil {
+			s.Failed++
+		} else {
+			s.Completed++
+		}
+		s.TotalSeconds += clock.Now().Sub(started).Seconds()
+		s.WaitSeconds += started.Sub(j.queued).Seconds()
+		p.mu.Unlock()
+	}
+}
+
+func (p *jobPool) Status() jobPoolStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := jobPoolStatus{
+		Workers:  p.workers,
+		Queued:   len(p.queue),
+		Capacity: cap(p.queue),
+		Running:  make([]runningJob, 0, len(p.running)),
+		Kinds:    make(map[string]jobKindStats, len(p.stats)),
+	}
+	for _, r := range p.running {
+		status.Running = append(status.Running, r)
+	}
+	slices.SortFunc(status.Running, func(a, b runningJob) int { return a.Started.Compare(b.Started) })
+	for kind, s := range p.stats {
+		status.Kinds[kind] = *s
+	}
+	return status
+}
+
+var jobs = newJobPool(defaultConfig().Jobs.QueueSize)
+
+func init() {
+	expvar.Publish("jobs", expvar.Func(func() any { return jobs.Status() }))
+}
@@ -0,0 +1,50 @@
+package main
+
+import "net/http"
+
+// manifestV1 is the manifest exactly as the first launchers shipped against
+// it. It is frozen: never add, rename or reinterpret fields here. New fields
+// belong on UpdaterResponse and reach v1 clients only through this adapter.
+type manifestV1 struct {
+	Version   string            `json:"version"`
+	PubDate   string            `json:"pub_date,omitempty"`
+	Url       string            `json:"url"`
+	FileName  string            `json:"fileName"`
+	Libraries map[string]string `json:"libraries"`
+}
+
+// toManifestV1 adapts the current manifest to v1. v1 launchers predate
+// timestamp normalization and expect the date as Nexus reported it.
+func toManifestV1(resp UpdaterResponse) manifestV1 {
+	pubDate := resp.LegacyPubDate
+	if pubDate == "" {
+		pubDate = resp.PubDate
+	}
+	return manifestV1{
+		Version:   resp.Version,
+		PubDate:   pubDate,
+		Url:       resp.Url,
+		FileName:  resp.FileName,
+		Libraries: resp.Libraries,
+	}
+}
+
+// wantsManifestV1 reports whether a latest.json request comes from a
+// launcher that predates capability negotiation. Those never send the
+// capabilities header, so they get the v1 shape on the path they always used;
+// launchers sending it, even empty, get the current manifest.
+func wantsManifestV1(r *http.Request) bool {
+	_, declared := r.Header[capabilitiesHeader]
+	return !declared && r.URL.Query().Get("compact") != "1"
+}
+
+func legacyLatestHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	recordCheckIn(ch)
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	resp = applyRollout(w.Header(), r, ch, resp)
+	writeJsonResponse(w, "manifest-v1", toManifestV1(resp))
+}
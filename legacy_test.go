@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestManifestV1Contract(t *testing.T) {
+	v1 := toManifestV1(testManifest())
+	if err := validateResponse("manifest-v1", v1); err != nil {
+		t.Errorf("v1 manifest does not match its schema: %v", err)
+	}
+	data, err := encodeJson(v1)
+	if err != nil {
+		t.Fatalf("encodeJson: %v", err)
+	}
+	checkGolden(t, "manifest-v1.json", data)
+}
+
+func TestLatestJsonShapeByCapabilities(t *testing.T) {
+	cacheTestManifest(t, testManifest())
+	tests := []struct {
+		name         string
+		path         string
+		capabilities []string
+		golden       string
+	}{
+		{"legacy launcher", "/selene-client/stable/latest.json", nil, "manifest-v1.json"},
+		{"frozen v1 path", "/selene-client/stable/v1/latest.json", []string{"sha256,stale"}, "manifest-v1.json"},
+		{"no capabilities", "/selene-client/stable/latest.json", []string{""}, "manifest.json"},
+		{"all capabilities", "/selene-client/stable/latest.json", []string{"sha256, assets,stale", "theme,next-check"}, "manifest-capabilities.json"},
+		{"compact with checksums", "/selene-client/stable/latest.json", []string{"compact,sha256"}, "manifest-compact.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			for _, c := range tt.capabilities {
+				r.Header.Add(capabilitiesHeader, c)
+			}
+			rec := serveTest(r)
+			if rec.Code != 200 {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			checkGolden(t, tt.golden, rec.Body.Bytes())
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// libraryChange is one dependency that differs from the previous release.
+// From is empty for added libraries and To for removed ones.
+type libraryChange struct {
+	Library string `json:"library"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+}
+
+type libraryChanges struct {
+	Since    string          `json:"since"`
+	Added    []libraryChange `json:"added,omitempty"`
+	Upgraded []libraryChange `json:"upgraded,omitempty"`
+	Removed  []libraryChange `json:"removed,omitempty"`
+}
+
+func (c libraryChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Upgraded) == 0 && len(c.Removed) == 0
+}
+
+// libraryChangeStore keeps the library changes of each release, keyed by
+// "group:artifact:version", so modders learn early when a dependency they
+// rely on changes.
+type libraryChangeStore struct {
+	mu      sync.RWMutex
+	path    string
+	changes map[string]libraryChanges
+}
+
+func newLibraryChangeStore(path string) *libraryChangeStore {
+	return &libraryChangeStore{path: path, changes: make(map[string]libraryChanges)}
+}
+
+var libraryChangeLog = newLibraryChangeStore("")
+
+func (s *libraryChangeStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.changes)
+}
+
+func (s *libraryChangeStore) Get(coordinates, version string) (libraryChanges, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	changes, ok := s.changes[coordinates+":"+version]
+	return changes, ok
+}
+
+// Record keeps the first changes computed for a version; channels that
+// reach it from a different previous release do not overwrite them.
+func (s *libraryChangeStore) Record(coordinates, version string, changes libraryChanges) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.changes[coordinates+":"+version]; ok {
+		return nil
+	}
+	s.changes[coordinates+":"+version] = changes
+	if s.path == "" {
+		return nil
+	}
+	return writeJsonFile(s.path, s.changes)
+}
+
+// libraryIdentity splits a library URL into "group:name[:classifier]" and
+// its version, using the Maven layout below the public repository. Other
+// URLs are identified by their file name alone.
+func libraryIdentity(url string) (string, string) {
+	rest, ok := strings.CutPrefix(url, publicRepositoryUrl())
+	parts := strings.Split(rest, "/")
+	if !ok || len(parts) < 4 {
+		return path.Base(url), ""
+	}
+	n := len(parts)
+	name, version, fileName := parts[n-3], parts[n-2], parts[n-1]
+	id := strings.Join(parts[:n-3], ".") + ":" + name
+	classifier := strings.TrimSuffix(fileName, path.Ext(fileName))
+	classifier = strings.TrimPrefix(strings.TrimPrefix(classifier, name+"-"+version), "-")
+	if classifier != "" {
+		id += ":" + classifier
+	}
+	return id, version
+}
+
+func libraryVersions(resp UpdaterResponse) map[string]string {
+	versions := make(map[string]string, len(resp.Libraries))
+	for _, url := range resp.Libraries {
+		id, version := libraryIdentity(url)
+		versions[id] = version
+	}
+	return versions
+}
+
+func diffLibraries(prev, next UpdaterResponse) libraryChanges {
+	changes := libraryChanges{Since: prev.Version}
+	before, after := libraryVersions(prev), libraryVersions(next)
+	for id, version := range after {
+		if old, ok := before[id]; !ok {
+			changes.Added = append(changes.Added, libraryChange{Library: id, To: version})
+		} else if old != version {
+			changes.Upgraded = append(changes.Upgraded, libraryChange{Library: id, From: old, To: version})
+		}
+	}
+	for id, version := range before {
+		if _, ok := after[id]; !ok {
+			changes.Removed = append(changes.Removed, libraryChange{Library: id, From: version})
+		}
+	}
+	for _, list := range [][]libraryChange{changes.Added, changes.Upgraded, changes.Removed} {
+		slices.SortFunc(list, func(a, b libraryChange) int {
+			return strings.Compare(a.Library, b.Library)
+		})
+	}
+	return changes
+}
+
+func recordLibraryChanges(key string, manifest UpdaterResponse, previousVersion string) error {
+	ch, err := parseChannelKey(key)
+	if err != nil {
+		return err
+	}
+	coordinates := ch.Group + ":" + ch.Artifact
+	if _, ok := libraryChangeLog.Get(coordinates, manifest.Version); ok {
+		return nil
+	}
+	previous, err := versionManifests.Do(key+"@"+previousVersion, versionManifestTTL, func() (UpdaterResponse, error) {
+		return releaseManifest(ch, previousVersion)
+	})
+	if err != nil {
+		return err
+	}
+	if err := libraryChangeLog.Record(coordinates, manifest.Version, diffLibraries(previous, manifest)); err != nil {
+		return err
+	}
+	// Changelogs include the changes, so drop the ones rendered without them
+	// along with version listings that predate the release.
+	changelogMemo.Clear()
+	changelogVersions.Clear()
+	return nil
+}
+
+func subscribeLibraryChanges(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type != EventReleaseDetected || e.Manifest == nil || e.PreviousVersion == "" {
+			return
+		}
+		manifest := *e.Manifest
+		jobs.Submit("library-changes", func() error {
+			return recordLibraryChanges(e.Channel, manifest, e.PreviousVersion)
+		})
+	})
+}
+
+// formatLibraryChanges renders changes as a Markdown list for the changelog.
+func formatLibraryChanges(changes libraryChanges) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "### Library changes since %s\n", changes.Since)
+	for _, c := range slices.Concat(changes.Added, changes.Upgraded, changes.Removed) {
+		sb.WriteString("\n- " + describeLibraryChange(c))
+	}
+	return sb.String()
+}
+
+func describeLibraryChange(c libraryChange) string {
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("Added %s %s", c.Library, c.To)
+	case c.To == "":
+		return fmt.Sprintf("Removed %s %s", c.Library, c.From)
+	default:
+		return fmt.Sprintf("Upgraded %s from %s to %s", c.Library, c.From, c.To)
+	}
+}
@@ -2,84 +2,172 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
+	"os"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type UpdaterResponse struct {
-	Version   string            `json:"version"`
-	PubDate   string            `json:"pub_date,omitempty"`
-	Url       string            `json:"url"`
-	FileName  string            `json:"fileName"`
-	Libraries map[string]string `json:"libraries"`
+	Version string `json:"version"`
+	PubDate string `json:"pub_date,omitempty"`
+	// Deprecated: LegacyPubDate carries the timestamp exactly as Nexus reported
+	// it, for clients that have not migrated to the RFC 3339 PubDate yet.
+	LegacyPubDate string            `json:"legacy_pub_date,omitempty"`
+	Url           string            `json:"url"`
+	FileName      string            `json:"fileName"`
+	Sha256        string            `json:"sha256,omitempty"`
+	Libraries     map[string]string `json:"libraries"`
+	// LibrarySha256 maps library file names to their SHA-256 checksums.
+	LibrarySha256 map[string]string `json:"librarySha256,omitempty"`
+	Assets        map[string]string `json:"assets,omitempty"`
+	// Stale marks the last known manifest, served while Nexus is unavailable.
+	Stale bool          `json:"stale,omitempty"`
+	Theme *ChannelTheme `json:"theme,omitempty"`
+	// NextCheckAfterSeconds is how long the launcher should wait before
+	// checking for updates again, tuned centrally per channel.
+	NextCheckAfterSeconds int `json:"nextCheckAfterSeconds,omitempty"`
 }
 
-func fetchLatestVersionWithAssets(repo, group, artifact string) (version, jarUrl, librariesUrl, pubDate string, err error) {
-	nexusUrl := "https://maven.twelveiterations.com/service/rest/v1/search"
-	query := fmt.Sprintf("repository=%s&group=%s&name=%s&sort=version", repo, group, artifact)
-	url := nexusUrl + "?" + query
+const negativeCacheTTL = 30 * time.Second
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", "", "", "", err
+// negativeCache briefly remembers lookups Nexus answered with 404. Other
+// failures may be transient and are never cached.
+var negativeCache = newNamedLruCache[string, error]("negative", 1024)
+
+type releaseAssets struct {
+	JarUrl       string
+	LibrariesUrl string
+	PubDate      string
+	Sha256       string
+	Extra        map[string]string
+}
+
+func selectReleaseAssets(item nexusItem, ch channel) (releaseAssets, error) {
+	var assets releaseAssets
+	if err := checkAssetContract(item, ch.Assets, ch.RequiredAssets); err != nil {
+		return assets, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return "", "", "", "", fmt.Errorf("Nexus API error: %s", resp.Status)
+	if asset, ok := item.findRole(ch.Assets, rolePrimary); ok {
+		assets.JarUrl = asset.DownloadUrl
+		assets.PubDate = asset.LastModified
+		assets.Sha256 = strings.ToLower(asset.Checksum.Sha256)
+		if assets.Sha256 == "" {
+			assets.Sha256, _ = fetchSha256Sidecar(asset.DownloadUrl)
+		}
 	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", "", "", "", err
+	if asset, ok := item.findRole(ch.Assets, roleLibraries); ok {
+		assets.LibrariesUrl = asset.DownloadUrl
 	}
-	var data struct {
-		Items []struct {
-			Version string `json:"version"`
-			Assets  []struct {
-				DownloadUrl  string `json:"downloadUrl"`
-				LastModified string `json:"lastModified,omitempty"`
-				Maven2       struct {
-					Classifier string `json:"classifier,omitempty"`
-					Extension  string `json:"extension,omitempty"`
-				} `json:"maven2"`
-			} `json:"assets"`
-		} `json:"items"`
+	if assets.JarUrl == "" {
+		return assets, fmt.Errorf("%w: no primary asset found for version %s", errNoRelease, item.Version)
 	}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return "", "", "", "", err
+	for _, role := range extraRoles(ch.Assets) {
+		if asset, ok := item.findRole(ch.Assets, role); ok {
+			if assets.Extra == nil {
+				assets.Extra = make(map[string]string)
+			}
+			assets.Extra[role] = transformToPublicUrl(asset.DownloadUrl)
+		}
 	}
-	if len(data.Items) == 0 {
-		return "", "", "", "", fmt.Errorf("No items found in Nexus response")
+	return assets, nil
+}
+
+func fetchLatestVersionWithAssets(ch channel, trace *resolveTrace) (version string, assets releaseAssets, err error) {
+	repo, group, artifact := ch.Repository, ch.Group, ch.Artifact
+	cacheKey := repo + ":" + group + ":" + artifact
+	if err, ok := negativeCache.Get(cacheKey); ok {
+		trace.Decide("negative cache hit: %v", err)
+		return "", assets, err
 	}
-	item := data.Items[0]
-	for _, asset := range item.Assets {
-		if asset.Maven2.Classifier == "dist" && asset.Maven2.Extension == "jar" {
-			jarUrl = asset.DownloadUrl
-			pubDate = asset.LastModified
+	started := time.Now()
+	page, err := searchNexus(repo, group, artifact, "", "")
+	trace.Call("search "+repo, started, err)
+	var statusErr *nexusStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		negativeCache.SetWithTTL(cacheKey, err, negativeCacheTTL)
+		return "", assets, err
+	} else if err != nil {
+		return "", assets, err
+	}
+	var contractErr error
+	for _, item := range page.Items {
+		if admin.IsBlocked(group+":"+artifact, item.Version) {
+			trace.Decide("skipped %s: blocked", item.Version)
+			continue
+		}
+		if !isSettled(group+":"+artifact+":"+item.Version, item, ch, time.Duration(config().SettlingMinutes)*time.Minute) {
+			trace.Decide("skipped %s: still settling", item.Version)
+			continue
 		}
-		if asset.Maven2.Classifier == "libraries" && asset.Maven2.Extension == "json" {
-			librariesUrl = asset.DownloadUrl
+		assets, err = selectReleaseAssets(item, ch)
+		if err != nil {
+			log.Printf("Warning: skipping incomplete release: %v", err)
+			trace.Decide("skipped %s: %v", item.Version, err)
+			if contractErr == nil {
+				contractErr = err
+			}
+			continue
 		}
+		trace.Decide("selected %s", item.Version)
+		return item.Version, assets, nil
 	}
-	if jarUrl == "" {
-		return item.Version, "", "", "", fmt.Errorf("No jar asset found for latest version")
+	err = contractErr
+	if err == nil {
+		err = fmt.Errorf("%w: no items found in Nexus response", errNoRelease)
 	}
-	return item.Version, jarUrl, librariesUrl, pubDate, nil
+	return "", releaseAssets{}, err
 }
 
-func fetchAndParseLibrariesJson(assetUrl string) (map[string]string, error) {
+func fetchVersionWithAssets(ch channel, version string, trace *resolveTrace) (releaseAssets, error) {
+	started := time.Now()
+	item, err := findNexusVersion(ch.Repository, ch.Group, ch.Artifact, version)
+	trace.Call("search "+ch.Repository+" version "+version, started, err)
+	if err != nil {
+		return releaseAssets{}, err
+	}
+	return selectReleaseAssets(item, ch)
+}
+
+// libraryList is the parsed libraries.json of a release: download URLs and
+// SHA-256 checksums, both keyed by file name.
+type libraryList struct {
+	Urls   map[string]string
+	Sha256 map[string]string
+	// Complete is false when checksums are missing because fetching them
+	// failed, rather than because they do not exist.
+	Complete bool
+}
+
+func fetchAndParseLibrariesJson(assetUrl string) (libraryList, error) {
 	if assetUrl == "" {
-		return nil, nil
+		return libraryList{}, nil
 	}
-	resp, err := http.Get(assetUrl)
+	if err, ok := negativeCache.Get(assetUrl); ok {
+		return libraryList{}, err
+	}
+	resp, err := upstreamGet(assetUrl)
 	if err != nil {
-		return nil, err
+		return libraryList{}, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		err := fmt.Errorf("Failed to fetch libraries asset: %w", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode})
+		negativeCache.SetWithTTL(assetUrl, err, negativeCacheTTL)
+		return libraryList{}, err
+	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Failed to fetch libraries asset: %s", resp.Status)
+		return libraryList{}, fmt.Errorf("Failed to fetch libraries asset: %w", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode})
 	}
 	var data struct {
 		Libraries []struct {
@@ -88,12 +176,13 @@ func fetchAndParseLibrariesJson(assetUrl string) (map[string]string, error) {
 			Version    string `json:"version"`
 			Classifier string `json:"classifier"`
 			Extension  string `json:"extension"`
+			Sha256     string `json:"sha256"`
 		} `json:"libraries"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+		return libraryList{}, err
 	}
-	libs := make(map[string]string)
+	libs := libraryList{Urls: make(map[string]string), Sha256: make(map[string]string)}
 	for _, lib := range data.Libraries {
 		classifier := ""
 		if lib.Classifier != "" {
@@ -104,69 +193,718 @@ func fetchAndParseLibrariesJson(assetUrl string) (map[string]string, error) {
 			extension = lib.Extension
 		}
 		fileName := fmt.Sprintf("%s-%s%s.%s", lib.Name, lib.Version, strings.ReplaceAll(classifier, ":", "-"), extension)
-		libs[fileName] = fmt.Sprintf("https://maven.twelveiterations.com/repository/selene-public/%s/%s/%s/%s", strings.ReplaceAll(lib.Group, ".", "/"), lib.Name, lib.Version, fileName)
+		libs.Urls[fileName] = fmt.Sprintf("%s%s/%s/%s/%s", publicRepositoryUrl(), strings.ReplaceAll(lib.Group, ".", "/"), lib.Name, lib.Version, fileName)
+		if sum := strings.ToLower(lib.Sha256); sha256Hex.MatchString(sum) {
+			libs.Sha256[fileName] = sum
+		}
 	}
+	libs.Complete = fillLibraryChecksums(libs)
 	return libs, nil
 }
 
+const checksumFetchConcurrency = 4
+
+// fillLibraryChecksums fetches the .sha256 sidecar of every library whose
+// checksum libraries.json did not declare. Libraries whose checksum cannot
+// be fetched are left out of the checksum map rather than failing the
+// manifest. It reports whether every sidecar was either fetched or does not
+// exist, so maps missing checksums only through upstream errors are not
+// cached.
+func fillLibraryChecksums(libs libraryList) bool {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var complete atomic.Bool
+	complete.Store(true)
+	sem := make(chan struct{}, checksumFetchConcurrency)
+	for fileName, url := range libs.Urls {
+		if _, ok := libs.Sha256[fileName]; ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			sum, err := fetchSha256Sidecar(url)
+			if err != nil {
+				log.Printf("Warning: no checksum for library %s: %v", fileName, err)
+				var statusErr *nexusStatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+					complete.Store(false)
+				}
+				return
+			}
+			mu.Lock()
+			libs.Sha256[fileName] = sum
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return complete.Load()
+}
+
+func fetchSha256Sidecar(fileUrl string) (string, error) {
+	resp, err := upstreamGet(fileUrl + ".sha256")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	// Sidecars are either the bare digest or sha256sum output.
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || !sha256Hex.MatchString(strings.ToLower(fields[0])) {
+		return "", fmt.Errorf("Invalid sha256 sidecar")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+var librariesCache = newNamedLruCache[string, libraryList]("libraries", 256)
+
+func fetchLibrariesForVersion(version, assetUrl string) (libraryList, error) {
+	libs, ok := librariesCache.Get(version)
+	recordCacheLookup("libraries", ok)
+	if ok {
+		return libs, nil
+	}
+	libs, err := fetchAndParseLibrariesJson(assetUrl)
+	if err != nil {
+		return libraryList{}, err
+	}
+	if libs.Complete {
+		librariesCache.Set(version, libs)
+	}
+	return libs, nil
+}
+
+// transformToPublicUrl rewrites download URLs from the internal hosted
+// repositories to the public group repository launchers can reach.
 func transformToPublicUrl(url string) string {
-	return strings.ReplaceAll(strings.ReplaceAll(url, "maven-releases", "selene-public"), "maven-snapshots", "selene-public")
+	for _, repo := range config().Nexus.InternalRepositories {
+		url = strings.ReplaceAll(url, "/repository/"+repo+"/", "/repository/"+config().Nexus.PublicRepository+"/")
+	}
+	return url
 }
 
 func extractFileName(url string) string {
 	return strings.Split(url, "/")[len(strings.Split(url, "/"))-1]
 }
 
-func gameHandler(w http.ResponseWriter, r *http.Request) {
+const manifestCacheTTL = time.Minute
+
+// channelCacheTTL is the configured manifest cache TTL for a channel's
+// branch, falling back to manifestCacheTTL. With background refresh the
+// refresh loop replaces manifests instead.
+func channelCacheTTL(ch channel) time.Duration {
+	if interval := refreshInterval(); interval > 0 {
+		return refreshTTLFactor * interval
+	}
+	if seconds, ok := config().CacheTtlSeconds[ch.Branch]; ok && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return manifestCacheTTL
+}
+
+var resolveFlights = newFlightGroup[string, UpdaterResponse]()
+
+var manifestCache = newNamedLruCache[string, UpdaterResponse]("manifest", 0)
+
+var lastServed = newManifestSnapshot("")
+
+func resolveChannel(ch channel) (UpdaterResponse, error) {
+	return resolveChannelTraced(ch, nil)
+}
+
+func resolveChannelTraced(ch channel, trace *resolveTrace) (UpdaterResponse, error) {
+	key := ch.Key()
+	resp, ok := manifestCache.Get(key)
+	recordCacheLookup("manifest", ok)
+	if ok {
+		if trace != nil {
+			trace.ManifestCache = "hit"
+		}
+		return resp, nil
+	}
+	// Concurrent misses for a channel share one resolve, errors included, so
+	// a cold start sends a single upstream fetch per channel no matter how
+	// many launchers are waiting for it.
+	resp, err, shared := resolveFlights.Do(key, func() (UpdaterResponse, error) {
+		if resp, ok := manifestCache.Get(key); ok {
+			if trace != nil {
+				trace.ManifestCache = "hit after wait"
+			}
+			return resp, nil
+		}
+		if trace != nil {
+			trace.ManifestCache = "miss"
+		}
+		return resolveUncached(ch, trace)
+	})
+	if shared && trace != nil {
+		trace.ManifestCache = "shared in-flight resolve"
+	}
+	return resp, err
+}
+
+func resolveUncached(ch channel, trace *resolveTrace) (UpdaterResponse, error) {
+	key := ch.Key()
+	if mirror != nil {
+		resp, err := mirror.Resolve(ch)
+		trace.Decide("served from mirror of %s", mirror.primary)
+		if err != nil {
+			return UpdaterResponse{}, err
+		}
+		storeResolved(ch, resp)
+		return resp, nil
+	}
+	if current, ok := releaseSlots.Current(key); ok && !admin.IsBlocked(ch.Group+":"+ch.Artifact, current.Version) {
+		trace.Decide("served from the current slot")
+		storeResolved(ch, current)
+		return current, nil
+	}
+	if releaseStore != nil {
+		resp, err := releaseStore.Resolve(ch)
+		trace.Decide("served from imported releases")
+		if err != nil {
+			return UpdaterResponse{}, err
+		}
+		storeResolved(ch, resp)
+		return resp, nil
+	}
+	var latestVersion string
+	var assets releaseAssets
+	var err error
+	pinned, isPinned := admin.Pin(key)
+	if isPinned && admin.IsBlocked(ch.Group+":"+ch.Artifact, pinned) {
+		log.Printf("Warning: ignoring pin of %s to yanked version %s", key, pinned)
+		trace.Decide("ignoring pin to yanked version %s", pinned)
+		isPinned = false
+	}
+	if isPinned {
+		latestVersion = pinned
+		if trace != nil {
+			trace.Pinned = pinned
+		}
+		assets, err = fetchVersionWithAssets(ch, pinned, trace)
+	} else {
+		latestVersion, assets, err = fetchLatestVersionWithAssets(ch, trace)
+	}
+	if err != nil {
+		events.Publish(Event{Type: EventResolutionFailed, Channel: key, Err: err})
+		if stale, ok := lastServed.Get(key); ok && !admin.IsBlocked(ch.Group+":"+ch.Artifact, stale.Version) {
+			log.Printf("Warning: serving last known manifest for %s: %v", key, err)
+			stale.Stale = true
+			manifestCache.SetWithTTL(key, stale, min(channelCacheTTL(ch), negativeCacheTTL))
+			if trace != nil {
+				trace.Stale = true
+			}
+			trace.Decide("serving last known manifest %s: %v", stale.Version, err)
+			return stale, nil
+		}
+		return UpdaterResponse{}, err
+	}
+
+	resp := buildManifest(ch, latestVersion, assets, trace)
+	if prev, ok := lastServed.Get(key); ok && !isPinned && !admin.IsBlocked(ch.Group+":"+ch.Artifact, prev.Version) {
+		done, err := smokeTestVerdict(ch, prev, resp)
+		if !done {
+			manifestCache.SetWithTTL(key, prev, min(channelCacheTTL(ch), negativeCacheTTL))
+			trace.Decide("held at %s while %s is smoke tested", prev.Version, resp.Version)
+			return prev, nil
+		}
+		if err != nil {
+			log.Printf("Warning: holding %s at %s, %s failed the smoke test: %v", key, prev.Version, resp.Version, err)
+			manifestCache.SetWithTTL(key, prev, channelCacheTTL(ch))
+			trace.Decide("held at %s, %s failed the smoke test: %v", prev.Version, resp.Version, err)
+			return prev, nil
+		}
+	}
+	storeResolved(ch, resp)
+	return resp, nil
+}
+
+// buildManifest assembles the manifest for a release from its assets,
+// fetching its libraries list.
+func buildManifest(ch channel, version string, assets releaseAssets, trace *resolveTrace) UpdaterResponse {
+	var libraries libraryList
+	if assets.LibrariesUrl != "" {
+		started := time.Now()
+		var err error
+		libraries, err = fetchLibrariesForVersion(ch.Group+":"+ch.Artifact+":"+version, transformToPublicUrl(assets.LibrariesUrl))
+		trace.Call("libraries "+version, started, err)
+		if err != nil {
+			log.Printf("Warning: failed to parse libraries asset: %v", err)
+		}
+	} else {
+		log.Printf("No libraries asset URL found")
+	}
+
+	return UpdaterResponse{
+		Version:       version,
+		PubDate:       normalizeTimestamp(assets.PubDate),
+		LegacyPubDate: assets.PubDate,
+		Url:           transformToPublicUrl(assets.JarUrl),
+		FileName:      extractFileName(assets.JarUrl),
+		Sha256:        assets.Sha256,
+		Libraries:     libraries.Urls,
+		LibrarySha256: libraries.Sha256,
+		Assets:        assets.Extra,
+	}
+}
+
+// releaseManifest returns the manifest of a channel's given version, or its
+// current one when version is empty.
+func releaseManifest(ch channel, version string) (UpdaterResponse, error) {
+	if version == "" {
+		return resolveChannel(ch)
+	}
+	if releaseStore != nil {
+		return releaseStore.Manifest(ch, version)
+	}
+	assets, err := fetchVersionWithAssets(ch, version, nil)
+	if err != nil {
+		return UpdaterResponse{}, err
+	}
+	return buildManifest(ch, version, assets, nil), nil
+}
+
+// storeResolved caches a freshly resolved manifest and publishes release and
+// update events when it differs from the one served last.
+func storeResolved(ch channel, resp UpdaterResponse) {
+	key := ch.Key()
+	manifestCache.SetWithTTL(key, resp, channelCacheTTL(ch))
+	if prev, ok := lastServed.Get(key); ok && prev.Version != resp.Version {
+		events.Publish(Event{Type: EventReleaseDetected, Channel: key, Version: resp.Version, PreviousVersion: prev.Version, Manifest: &resp})
+	}
+	changed, err := lastServed.Update(key, resp)
+	if err != nil {
+		log.Printf("Warning: failed to write manifest snapshot: %v", err)
+	}
+	if changed {
+		events.Publish(Event{Type: EventManifestUpdated, Channel: key, Version: resp.Version, Manifest: &resp})
+	}
+}
+
+func channelHandler(w http.ResponseWriter, r *http.Request) {
 	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(segments) != 3 || segments[0] != "selene-client" || segments[2] != "latest.json" {
+	if len(segments) == 2 && segments[1] == "channels.json" {
+		channelsHandler(w, r, segments[0])
+		return
+	}
+	if len(segments) < 3 {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if serveRetired(w, segments) {
+		return
+	}
+	ch, err := lookupChannel(segments[0], segments[1])
+	if err != nil {
+		writeFailure(w, "", err)
+		return
+	}
+	if ch.Private && !validUrlSignature(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if mirror != nil && !slices.Contains([]string{"latest.json", "latest.pb"}, segments[len(segments)-1]) {
+		mirror.proxy.ServeHTTP(w, r)
+		return
+	}
+	if len(segments) == 4 && segments[2] == "provenance" {
+		provenanceHandler(w, r, ch, segments[3])
+		return
+	} else if len(segments) == 4 && segments[2] == "readiness" {
+		readinessHandler(w, r, ch, segments[3])
+		return
+	} else if len(segments) >= 4 && segments[2] == "getdown" {
+		getdownHandler(w, r, ch, strings.Join(segments[3:], "/"))
+		return
+	} else if len(segments) == 4 && segments[2] == "squirrel" {
+		squirrelHandler(w, r, ch, segments[3])
+		return
+	} else if len(segments) == 4 && segments[2] == "v1" && segments[3] == "latest.json" {
+		legacyLatestHandler(w, r, ch)
+		return
+	} else if len(segments) != 3 {
 		http.Error(w, "Not found", http.StatusNotFound)
 		return
 	}
-	var repo string
-	switch segments[1] {
-	case "stable":
-		repo = "maven-snapshots" // TODO for now, until we have a first stable release
-	case "experimental":
-		repo = "maven-snapshots"
+	switch segments[2] {
+	case "latest.json":
+		latestHandler(w, r, ch)
+	case "latest.pb":
+		latestProtoHandler(w, r, ch)
+	case "changelog":
+		changelogHandler(w, r, ch, "")
+	case "changelog.md":
+		changelogHandler(w, r, ch, "md")
+	case "changelog.html":
+		changelogHandler(w, r, ch, "html")
+	case "feed.xml":
+		feedHandler(w, r, ch)
+	case "appcast.xml":
+		appcastHandler(w, r, ch)
+	case "events":
+		eventsHandler(w, r, ch)
+	case "tauri.json":
+		tauriHandler(w, r, ch)
+	case "update4j.xml":
+		update4jHandler(w, r, ch)
 	default:
+		if version, ok := strings.CutSuffix(segments[2], ".json"); ok && version != "" {
+			versionHandler(w, r, ch, version)
+			return
+		}
 		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+var requestsByChannel = expvar.NewMap("requests_by_channel")
+
+func channelRequests(key string) int64 {
+	if v, ok := requestsByChannel.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func latestHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	p, err := parsePlatform(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	recordCheckIn(ch)
+	var trace *resolveTrace
+	if debugAllowed(r) {
+		trace = &resolveTrace{}
+	}
+	serveLatest(w, r, ch, p, trace)
+}
 
-	latestVersion, jarUrl, librariesUrl, pubDate, err := fetchLatestVersionWithAssets(repo, "world.selene", "selene-client")
+// serveLatest answers an update check for platform p. Simulations run it for
+// made-up clients, so it must not record anything about the request.
+func serveLatest(w http.ResponseWriter, r *http.Request, ch channel, p platform, trace *resolveTrace) {
+	m, err := latestResponse(r, ch, p, trace)
 	if err != nil {
-		log.Printf("Warning: failed to fetch latest version: %v", err)
-		http.Error(w, "Failed to fetch latest version", http.StatusInternalServerError)
+		writeTracedFailure(w, "Failed to fetch latest version", err, trace)
 		return
 	}
+	maps.Copy(w.Header(), m.Header)
+	writeJsonResponse(w, m.Schema, m.Body)
+}
 
-	var libraries map[string]string
-	if librariesUrl != "" {
-		libraries, err = fetchAndParseLibrariesJson(transformToPublicUrl(librariesUrl))
-		if err != nil {
-			log.Printf("Warning: failed to parse libraries asset: %v", err)
-		}
-	} else {
-		log.Printf("No libraries asset URL found")
+// latestManifest is what latest.json answers a request with: the headers
+// and the body, with the schema it is written against.
+type latestManifest struct {
+	Header http.Header
+	Schema string
+	Body   any
+}
+
+// latestResponse decides what latest.json serves a request without writing
+// it, so simulated clients get the same answer as real ones.
+func latestResponse(r *http.Request, ch channel, p platform, trace *resolveTrace) (latestManifest, error) {
+	started := time.Now()
+	resp, err := resolveChannelTraced(ch, trace)
+	if trace != nil {
+		trace.Milliseconds = float64(time.Since(started).Microseconds()) / 1000
 	}
+	if err != nil {
+		return latestManifest{}, err
+	}
+	m := latestManifest{Header: make(http.Header)}
+	resp = applyRollout(m.Header, r, ch, resp)
+	markStale(m.Header, resp)
+	resp.Theme = channelTheme(ch)
+	resp.NextCheckAfterSeconds = nextCheckAfter(ch)
+	caps := negotiateCapabilities(m.Header, r)
+	resp = tailorManifest(filterLibraries(resp, p, ch.Platforms), caps)
+	switch {
+	case trace != nil:
+		m.Schema, m.Body = "manifest-debug", debugManifest{UpdaterResponse: resp, Debug: trace}
+	case wantsManifestV1(r):
+		m.Schema, m.Body = "manifest-v1", toManifestV1(resp)
+	case r.URL.Query().Get("compact") == "1" || caps[capabilityCompact]:
+		m.Schema, m.Body = "manifest-compact", compactManifest(resp)
+	default:
+		m.Schema, m.Body = "manifest", resp
+	}
+	return m, nil
+}
 
-	resp := UpdaterResponse{
-		Version:   latestVersion,
-		PubDate:   pubDate,
-		Url:       transformToPublicUrl(jarUrl),
-		FileName:  extractFileName(jarUrl),
-		Libraries: libraries,
+// versionManifests caches manifests of specific versions, which unlike the
+// latest one never change.
+var versionManifests = newMemoizer[string, UpdaterResponse]("versions", 256)
+
+const versionManifestTTL = time.Hour
+
+// versionHandler serves the manifest of one specific version, so clients can
+// roll back or reproduce an old install.
+func versionHandler(w http.ResponseWriter, r *http.Request, ch channel, version string) {
+	p, err := parsePlatform(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	if admin.IsBlocked(ch.Group+":"+ch.Artifact, version) {
+		http.Error(w, "Version yanked", http.StatusGone)
+		return
+	}
+	resp, err := versionManifests.Do(ch.Key()+"@"+version, versionManifestTTL, func() (UpdaterResponse, error) {
+		return releaseManifest(ch, version)
+	})
+	if err != nil {
+		writeFailure(w, "Failed to fetch version "+version, err)
+		return
+	}
+	resp.Theme = channelTheme(ch)
+	caps := negotiateCapabilities(w.Header(), r)
+	resp = tailorManifest(filterLibraries(resp, p, ch.Platforms), caps)
+	if r.URL.Query().Get("compact") == "1" || caps[capabilityCompact] {
+		writeJsonResponse(w, "manifest-compact", compactManifest(resp))
+		return
+	}
+	writeJsonResponse(w, "manifest", resp)
+}
+
+// markStale flags a last known manifest in the headers too, which reach
+// launchers that do not declare the stale capability.
+func markStale(h http.Header, resp UpdaterResponse) {
+	if resp.Stale {
+		h.Set("X-Selene-Stale", "true")
+	}
+}
+
+func latestProtoHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	p, err := parsePlatform(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recordCheckIn(ch)
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	resp = applyRollout(w.Header(), r, ch, resp)
+	markStale(w.Header(), resp)
+	resp = filterLibraries(resp, p, ch.Platforms)
+	resp.Theme = channelTheme(ch)
+	resp.NextCheckAfterSeconds = nextCheckAfter(ch)
+	data := encodeManifestProto(resp)
+	signManifestResponse(w, data)
+	tlog.Record(data)
+	writeBody(w, protoContentType, data)
 }
 
 func main() {
-	http.HandleFunc("/selene-client/", gameHandler)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.NotFound(w, r)
-	})
-	log.Println("Serving endpoint at http://localhost:8080/selene-client/{branch}/latest.json")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	configPath := flag.String("config", "", "path to a JSON config file")
+	profile := flag.String("profile", os.Getenv("SELENE_PROFILE"), "config profile layered on top of the config file, e.g. dev or prod")
+	flag.Parse()
+
+	if flag.Arg(0) == "top" {
+		if err := runTop(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "version" {
+		fmt.Println(currentBuild())
+		return
+	}
+	if flag.Arg(0) == "admin" {
+		if err := runAdminCli(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(*configPath, *profile)
+	if err == nil {
+		err = validateConfigValues(cfg)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config:\n%v", err)
+	}
+	setConfig(cfg)
+	clock = newCorrectedClock(time.Duration(config().Clock.SkewToleranceSeconds) * time.Second)
+	upstreamBudget = newRequestBudget(config().Upstream.RequestsPerMinute)
+	if config().UrlSigningSecret != "" {
+		urlSigningKey = []byte(config().UrlSigningSecret)
+	}
+	artifacts = newArtifactAllowlist(config().Artifacts)
+	lastServed = newManifestSnapshot(config().SnapshotPath)
+	if err := lastServed.Load(); err != nil {
+		log.Printf("Warning: failed to load manifest snapshot: %v", err)
+	}
+	admin = newAdminState(config().BlocklistPath, config().ReleaseHistoryPath)
+	if err := admin.LoadBlocklist(); err != nil {
+		log.Fatalf("Failed to load blocklist: %v", err)
+	}
+	if err := admin.LoadHistory(); err != nil {
+		log.Fatalf("Failed to load release history: %v", err)
+	}
+	admin.BlockByConfig(*config())
+	rollouts = newRolloutStore(config().RolloutsPath)
+	if err := rollouts.Load(); err != nil {
+		log.Fatalf("Failed to load rollouts: %v", err)
+	}
+	releaseSlots = newSlotStore(config().SlotsPath)
+	if err := releaseSlots.Load(); err != nil {
+		log.Fatalf("Failed to load manifest slots: %v", err)
+	}
+	libraryChangeLog = newLibraryChangeStore(config().LibraryChangesPath)
+	if err := libraryChangeLog.Load(); err != nil {
+		log.Printf("Warning: failed to load library changes: %v", err)
+	}
+	modWatches = newModWatchStore(config().ModWatchesPath)
+	if err := modWatches.Load(); err != nil {
+		log.Fatalf("Failed to load mod registrations: %v", err)
+	}
+	rebuilds = newRebuildStore(config().RebuildsPath)
+	if err := rebuilds.Load(); err != nil {
+		log.Printf("Warning: failed to load rebuild attestations: %v", err)
+	}
+
+	if config().Filesystem != nil {
+		if config().Mirror != nil {
+			log.Fatalf("Failed to load config: mirror and filesystem cannot be combined")
+		}
+		if releaseStore, err = newFilesystemBackend(*config().Filesystem); err != nil {
+			log.Fatalf("Failed to configure filesystem backend: %v", err)
+		}
+	}
+
+	if flag.Arg(0) == "export" {
+		if err := runExport(flag.Args()[1:]); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(flag.Args()[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	jobs = newJobPool(config().Jobs.QueueSize)
+	jobs.Start(config().Jobs.Workers)
+	schedule, err := newScheduler(config().Schedules, refreshInterval())
+	if err != nil {
+		log.Fatalf("Failed to configure schedules: %v", err)
+	}
+	go runScheduler(schedule)
+	subscribeCacheInvalidation(events)
+	subscribeErrorLog(events)
+	subscribeReleaseHistory(events)
+	subscribeDownloadSizes(events)
+	subscribeLibraryChanges(events)
+	subscribeModWatches(events)
+	releaseWatches.Subscribe(events)
+	deadLetters := newDeadLetterStore(config().DeadLetterPath)
+	if err := deadLetters.Load(); err != nil {
+		log.Fatalf("Failed to load dead letters: %v", err)
+	}
+	notifications, err = newNotificationDispatcher(config().Notifiers, deadLetters)
+	if err != nil {
+		log.Fatalf("Failed to configure notifiers: %v", err)
+	}
+	notifications.Subscribe(events)
+	tlog = newTransparencyLog(config().TransparencyLogPath)
+	if err := tlog.Load(); err != nil {
+		log.Fatalf("Failed to load transparency log: %v", err)
+	}
+	if config().EventExport != nil {
+		exporter, err := newEventExporter(*config().EventExport)
+		if err != nil {
+			log.Fatalf("Failed to configure event export: %v", err)
+		}
+		exporter.Subscribe(events)
+		go exporter.Run()
+	}
+	if config().GitPublish != nil {
+		publisher := newGitPublisher(*config().GitPublish)
+		publisher.Subscribe(events)
+		go publisher.Run()
+	}
+
+	if releaseStore != nil {
+		http.Handle("/files/", releaseStore.FileServer())
+	}
+	var feed *syncFeed
+	if config().Mirror != nil {
+		if mirror, err = newMirrorSync(*config().Mirror); err != nil {
+			log.Fatalf("Failed to configure mirror: %v", err)
+		}
+		if config().Mirror.PublicKey != "" {
+			if feed, err = newReplicaSyncFeed(config().SyncFeedPath, config().Mirror.PublicKey); err != nil {
+				log.Fatalf("Failed to configure mirror: %v", err)
+			}
+			mirror.feed = feed
+		}
+		go mirror.Run()
+		if config().Mirror.Artifacts {
+			http.Handle("/files/", mirror.FileServer())
+		}
+	} else if config().SyncSigningKey != "" {
+		if feed, err = newSigningSyncFeed(config().SyncFeedPath, config().SyncSigningKey); err != nil {
+			log.Fatalf("Failed to configure sync feed: %v", err)
+		}
+		feed.Subscribe(events)
+		manifestSigningKey = feed.key
+	}
+	if config().SignManifests && manifestSigningKey == nil {
+		log.Fatalf("Failed to load config: signManifests requires syncSigningKey and is not available on mirrors")
+	}
+	if feed != nil {
+		if err := feed.Load(); err != nil {
+			log.Fatalf("Failed to load sync feed: %v", err)
+		}
+		manifestFeed = feed
+		http.Handle("/sync/", feed)
+	}
+	http.HandleFunc("/healthz", healthHandler)
+	http.HandleFunc("/readyz", readyHandler)
+	http.HandleFunc("/healthz/deep", deepHealthHandler)
+	http.HandleFunc("/version", buildInfoHandler)
+	http.HandleFunc("/keys", keysHandler)
+	http.HandleFunc("/metrics", metricsHandler)
+	http.Handle("/transparency/", tlog)
+	http.HandleFunc("/rebuilds/", rebuildHandler)
+	http.HandleFunc("/hooks/", hookHandler)
+	http.HandleFunc("/admin/", adminHandler)
+	http.HandleFunc("/batch.json", batchHandler)
+	http.HandleFunc("/artifacts.json", artifactsHandler)
+	http.HandleFunc("/schemas/", schemaHandler)
+	http.HandleFunc("/stats/usage", usageHandler)
+	http.HandleFunc("/search", newClientRateLimiter(config().SearchRequestsPerMinute).Wrap(searchHandler))
+	http.HandleFunc("/", channelHandler)
+	applyCacheLimits(config().Caches)
+	go reloadConfigOnHangup(*configPath, *profile)
+	if config().Oidc != nil {
+		if oidc, err = newOidcProvider(*config().Oidc); err != nil {
+			log.Fatalf("Failed to configure OIDC: %v", err)
+		}
+	}
+	access, err := newAccessControl(config().AccessRules)
+	if err != nil {
+		log.Fatalf("Failed to configure access rules: %v", err)
+	}
+	go monitorClockDrift(config().Clock)
+	go warmup(warmupTimeout)
+	if config().GrpcListen != "" {
+		go serveGrpc(config().GrpcListen, access)
+	}
+	log.Printf("Starting %s", currentBuild())
+	log.Printf("Listening on %s, serving /{product}/{branch}/latest.json", config().Listen)
+	log.Fatal(http.ListenAndServe(config().Listen, instrument(access.Wrap(http.DefaultServeMux))))
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testManifest has every field set, so contract tests notice when a field
+// leaks into a shape that must not carry it.
+func testManifest() UpdaterResponse {
+	return UpdaterResponse{
+		Version:       "1.2.0",
+		PubDate:       "2025-01-01T12:00:00Z",
+		LegacyPubDate: "2025-01-01T12:00:00.000+0000",
+		Url:           "https://maven.example.com/repository/selene-public/world/selene/selene-client/1.2.0/selene-client-1.2.0-dist.jar",
+		FileName:      "selene-client-1.2.0-dist.jar",
+		Sha256:        "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		Libraries: map[string]string{
+			"gson-2.10.1.jar":                 "https://repo1.maven.org/maven2/com/google/code/gson/gson/2.10.1/gson-2.10.1.jar",
+			"lwjgl-3.3.3-natives-windows.jar": "https://repo1.maven.org/maven2/org/lwjgl/lwjgl/3.3.3/lwjgl-3.3.3-natives-windows.jar",
+		},
+		LibrarySha256: map[string]string{
+			"gson-2.10.1.jar": "4233a0ef3e9d2d5b8a3d6e1e0c4a2e8b2d8c2a6bd1c1ea7a25d8c0d8e4c6b1f2",
+		},
+		Assets:                map[string]string{"installer": "https://maven.example.com/selene-client-1.2.0-installer.exe"},
+		Stale:                 true,
+		Theme:                 &ChannelTheme{DisplayName: "Stable"},
+		NextCheckAfterSeconds: 3600,
+	}
+}
+
+// cacheTestManifest makes resp the cached manifest of selene-client/stable
+// for the duration of the test, so handlers serve it without Nexus.
+func cacheTestManifest(t *testing.T, resp UpdaterResponse) channel {
+	t.Helper()
+	ch, err := lookupChannel("selene-client", "stable")
+	if err != nil {
+		t.Fatalf("lookupChannel: %v", err)
+	}
+	manifestCache.Set(ch.Key(), resp)
+	t.Cleanup(func() { manifestCache.Delete(ch.Key()) })
+	return ch
+}
+
+// serveTest runs a request through the channel router.
+func serveTest(r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	channelHandler(rec, r)
+	return rec
+}
+
+// checkGolden compares got with testdata/name, rewriting it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match:\ngot:  %s\nwant: %s", path, got, want)
+	}
+}
+
+// useNexus points the server at url for the duration of the test, dropping
+// whatever was cached from another upstream. The request budget is lifted,
+// as tests resolve more often than a real server would.
+func useNexus(t *testing.T, url string) {
+	t.Helper()
+	served, budget := lastServed, upstreamBudget
+	useConfig(t, func(cfg *Config) { cfg.Nexus.Url = url })
+	lastServed = newManifestSnapshot("")
+	upstreamBudget = newRequestBudget(0)
+	clearCaches := func() {
+		flushCaches()
+		librariesCache.Clear()
+		searchCache.Clear()
+		deepHealthChecks.Clear()
+	}
+	clearCaches()
+	t.Cleanup(func() {
+		lastServed, upstreamBudget = served, budget
+		clearCaches()
+	})
+}
+
+// useConfig applies change to a copy of the config for the duration of the
+// test.
+func useConfig(t *testing.T, change func(cfg *Config)) {
+	t.Helper()
+	previous := config()
+	cfg := *previous
+	change(&cfg)
+	setConfig(cfg)
+	t.Cleanup(func() { currentConfig.Store(previous) })
+}
+
+// startFakeNexus serves selene-client 1.2.0 and 1.1.0 the way Nexus does,
+// each with a dist jar and a libraries.json, and uses it for the test.
+func startFakeNexus(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	item := func(version string) nexusItem {
+		base := server.URL + "/repository/maven-snapshots/world/selene/selene-client/" + version + "/selene-client-" + version
+		dist := nexusAsset{DownloadUrl: base + "-dist.jar", LastModified: "2025-01-01T00:00:00.000+00:00"}
+		dist.Maven2.Classifier, dist.Maven2.Extension = "dist", "jar"
+		libraries := nexusAsset{DownloadUrl: base + "-libraries.json", LastModified: "2025-01-01T00:00:00.000+00:00"}
+		libraries.Maven2.Classifier, libraries.Maven2.Extension = "libraries", "json"
+		return nexusItem{Version: version, Assets: []nexusAsset{dist, libraries}}
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := r.URL.Path; {
+		case path == "/service/rest/v1/status":
+		case path == "/service/rest/v1/search":
+			var page nexusSearchPage
+			for _, version := range []string{"1.2.0", "1.1.0"} {
+				if want := r.URL.Query().Get("version"); want == "" || want == version {
+					page.Items = append(page.Items, item(version))
+				}
+			}
+			json.NewEncoder(w).Encode(page)
+		case strings.HasSuffix(path, "-libraries.json"):
+			fmt.Fprint(w, `{"libraries":[{"group":"org.lwjgl","name":"lwjgl","version":"3.3.3"},{"group":"org.lwjgl","name":"lwjgl","version":"3.3.3","classifier":"natives-windows"}]}`)
+		case strings.HasSuffix(path, ".sha256"):
+			sum := sha256.Sum256([]byte(filepath.Base(strings.TrimSuffix(path, ".sha256"))))
+			fmt.Fprintf(w, "%s  file\n", hex.EncodeToString(sum[:]))
+		case strings.HasSuffix(path, ".jar"):
+			fmt.Fprint(w, filepath.Base(path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	useNexus(t, server.URL)
+	return server
+}
+
+func TestRequestBudgetAdmitsNoBurst(t *testing.T) {
+	offset := clock.offset.Load()
+	t.Cleanup(func() { clock.offset.Store(offset) })
+	advance := func(d time.Duration) { clock.offset.Add(int64(d)) }
+
+	budget := newRequestBudget(3)
+	for i := range 3 {
+		if !budget.Take() {
+			t.Fatalf("Request %d was refused", i+1)
+		}
+		advance(10 * time.Second)
+	}
+	if budget.Take() {
+		t.Fatal("A fourth request within the minute was admitted")
+	}
+	// Refilling would have admitted another by now; only the first request
+	// has left the window.
+	advance(31 * time.Second)
+	if !budget.Take() {
+		t.Fatal("A request was refused after the first one left the window")
+	}
+	if budget.Take() {
+		t.Fatal("More than three requests were admitted in one minute")
+	}
+	if remaining, _ := budget.Remaining(); remaining != 0 {
+		t.Errorf("Remaining is %d, want 0", remaining)
+	}
+	if retry := budget.RetryAfter(); retry <= 0 || retry > 10*time.Second {
+		t.Errorf("RetryAfter is %s, want up to 10s", retry)
+	}
+}
+
+func TestDownloadsSpendUpstreamBudget(t *testing.T) {
+	server := startFakeNexus(t)
+	upstreamBudget = newRequestBudget(1)
+	url := server.URL + "/repository/maven-snapshots/world/selene/selene-client/1.2.0/selene-client-1.2.0-dist.jar"
+	if err := checkResolvable(url); err != nil {
+		t.Fatalf("First check failed: %v", err)
+	}
+	if err := checkResolvable(url); !errors.Is(err, errUpstreamBudgetExceeded) {
+		t.Errorf("Second check returned %v, want the budget to be exceeded", err)
+	}
+}
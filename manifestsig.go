@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+)
+
+// With signManifests enabled, manifest responses carry an Ed25519 signature
+// of the exact response body by the server's signing key (syncSigningKey),
+// so launchers can detect manifests altered by a MITM or a compromised CDN.
+// Launchers pin the key published at /keys.
+const (
+	manifestSignatureHeader = "X-Selene-Manifest-Signature"
+	manifestKeyIdHeader     = "X-Selene-Manifest-Key-Id"
+)
+
+// signedSchemas are the response schemas that are manifests. Debug
+// responses are left unsigned.
+var signedSchemas = map[string]bool{"manifest": true, "manifest-v1": true, "manifest-compact": true}
+
+func manifestKeyId(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	return hex.EncodeToString(sum[:8])
+}
+
+func signManifestResponse(w http.ResponseWriter, body []byte) {
+	if !config().SignManifests || manifestSigningKey == nil {
+		return
+	}
+	w.Header().Set(manifestSignatureHeader, base64.StdEncoding.EncodeToString(ed25519.Sign(manifestSigningKey, body)))
+	w.Header().Set(manifestKeyIdHeader, manifestKeyId(manifestSigningKey.Public().(ed25519.PublicKey)))
+}
+
+type publicKey struct {
+	KeyId     string `json:"keyId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+}
+
+func keysHandler(w http.ResponseWriter, r *http.Request) {
+	keys := []publicKey{}
+	if manifestSigningKey != nil {
+		public := manifestSigningKey.Public().(ed25519.PublicKey)
+		keys = append(keys, publicKey{KeyId: manifestKeyId(public), Algorithm: "ed25519", PublicKey: base64.StdEncoding.EncodeToString(public)})
+	}
+	writeJson(w, map[string]any{"keys": keys})
+}
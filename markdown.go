@@ -0,0 +1,168 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	markdownHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	markdownBullet      = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	markdownOrdered     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	markdownRule        = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	markdownLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownStrong      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownEmphasis    = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	markdownSafeSchemes = []string{"http", "https", "mailto"}
+)
+
+// renderMarkdown converts the subset of Markdown used in release notes to HTML.
+// Raw HTML in the input is always escaped, so the output is safe to embed.
+func renderMarkdown(src string) string {
+	var out strings.Builder
+	var paragraph []string
+	listTag := ""
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + renderMarkdownInline(strings.Join(paragraph, " ")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			out.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			out.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCode {
+				out.WriteString("</code></pre>\n")
+			} else {
+				flushParagraph()
+				closeList()
+				out.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			closeList()
+			level := strconv.Itoa(len(m[1]))
+			out.WriteString("<h" + level + ">" + renderMarkdownInline(m[2]) + "</h" + level + ">\n")
+			continue
+		}
+		if markdownRule.MatchString(line) {
+			flushParagraph()
+			closeList()
+			out.WriteString("<hr>\n")
+			continue
+		}
+		if m := markdownBullet.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			openList("ul")
+			out.WriteString("<li>" + renderMarkdownInline(m[1]) + "</li>\n")
+			continue
+		}
+		if m := markdownOrdered.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			openList("ol")
+			out.WriteString("<li>" + renderMarkdownInline(m[1]) + "</li>\n")
+			continue
+		}
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), ">"); ok {
+			flushParagraph()
+			closeList()
+			out.WriteString("<blockquote>" + renderMarkdownInline(strings.TrimSpace(rest)) + "</blockquote>\n")
+			continue
+		}
+		closeList()
+		paragraph = append(paragraph, strings.TrimSpace(line))
+	}
+	if inCode {
+		out.WriteString("</code></pre>\n")
+	}
+	flushParagraph()
+	closeList()
+	return out.String()
+}
+
+func renderMarkdownInline(text string) string {
+	var out strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		if i%2 == 1 && i < len(parts)-1 {
+			out.WriteString("<code>" + html.EscapeString(part) + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			out.WriteString("`")
+		}
+		out.WriteString(renderMarkdownSpan(part))
+	}
+	return out.String()
+}
+
+// renderMarkdownSpan splits text at links before applying emphasis, so
+// underscores and asterisks in URLs are left alone.
+func renderMarkdownSpan(text string) string {
+	var out strings.Builder
+	escaped := html.EscapeString(text)
+	last := 0
+	for _, m := range markdownLink.FindAllStringSubmatchIndex(escaped, -1) {
+		out.WriteString(renderMarkdownEmphasis(escaped[last:m[0]]))
+		label := renderMarkdownEmphasis(escaped[m[2]:m[3]])
+		if href := html.UnescapeString(escaped[m[4]:m[5]]); isSafeMarkdownUrl(href) {
+			out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">` + label + "</a>")
+		} else {
+			out.WriteString(label)
+		}
+		last = m[1]
+	}
+	out.WriteString(renderMarkdownEmphasis(escaped[last:]))
+	return out.String()
+}
+
+func renderMarkdownEmphasis(escaped string) string {
+	escaped = markdownStrong.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	return markdownEmphasis.ReplaceAllString(escaped, "<em>$1$2</em>")
+}
+
+func isSafeMarkdownUrl(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		return !strings.HasPrefix(raw, "//")
+	}
+	for _, scheme := range markdownSafeSchemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return true
+		}
+	}
+	return false
+}
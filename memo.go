@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type memoCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// flightGroup runs each key at most once at a time: callers arriving while a
+// computation is in flight wait for it and share its result, error included.
+type flightGroup[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*memoCall[V]
+}
+
+func newFlightGroup[K comparable, V any]() *flightGroup[K, V] {
+	return &flightGroup[K, V]{calls: make(map[K]*memoCall[V])}
+}
+
+// Do reports whether the result was shared from another caller's computation.
+func (g *flightGroup[K, V]) Do(key K, compute func() (V, error)) (V, error, bool) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.value, call.err, true
+	}
+	call := &memoCall[V]{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.value, call.err = compute()
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return call.value, call.err, false
+}
+
+// memoizer computes each key at most once at a time and keeps successful
+// results in a bounded LRU, so identical expensive requests that arrive
+// together or in quick succession share a single computation.
+type memoizer[K comparable, V any] struct {
+	results *lruCache[K, V]
+	flights *flightGroup[K, V]
+}
+
+// newMemoizer registers its results under name unless name is empty.
+func newMemoizer[K comparable, V any](name string, maxEntries int) *memoizer[K, V] {
+	results := newLruCache[K, V](maxEntries)
+	if name != "" {
+		namedCaches[name] = results
+	}
+	return &memoizer[K, V]{results: results, flights: newFlightGroup[K, V]()}
+}
+
+func (m *memoizer[K, V]) Do(key K, ttl time.Duration, compute func() (V, error)) (V, error) {
+	if v, ok := m.results.Get(key); ok {
+		return v, nil
+	}
+	v, err, _ := m.flights.Do(key, func() (V, error) {
+		v, err := compute()
+		if err == nil {
+			m.results.SetWithTTL(key, v, ttl)
+		}
+		return v, err
+	})
+	return v, err
+}
+
+func (m *memoizer[K, V]) Clear() {
+	m.results.Clear()
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics are rendered by hand in the Prometheus text exposition format. The
+// expvar counters stay the source of truth where they already exist; the
+// histograms and cache lookups below are only tracked for /metrics.
+
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+type histogramVec struct {
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+func newHistogramVec() *histogramVec {
+	return &histogramVec{series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) Observe(label string, d time.Duration) {
+	seconds := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[label]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		h.series[label] = s
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += seconds
+}
+
+func (h *histogramVec) write(w io.Writer, name, labelName, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, label := range sortedKeys(h.series) {
+		s := h.series[label]
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "%s_bucket{%s=%q,le=%q} %d\n", name, labelName, label, strconv.FormatFloat(bound, 'g', -1, 64), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s=%q,le=\"+Inf\"} %d\n", name, labelName, label, s.count)
+		fmt.Fprintf(w, "%s_sum{%s=%q} %g\n", name, labelName, label, s.sum)
+		fmt.Fprintf(w, "%s_count{%s=%q} %d\n", name, labelName, label, s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+var (
+	requestDurations  = newHistogramVec()
+	upstreamDurations = newHistogramVec()
+	responsesByCode   = expvar.NewMap("responses_by_code")
+	cacheLookups      = expvar.NewMap("cache_lookups")
+)
+
+// recordCacheLookup counts a hit or miss for the named cache.
+func recordCacheLookup(cache string, hit bool) {
+	if hit {
+		cacheLookups.Add(cache+":hit", 1)
+	} else {
+		cacheLookups.Add(cache+":miss", 1)
+	}
+}
+
+// endpointLabel buckets request paths into a small, fixed set of labels.
+func endpointLabel(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/latest.json"):
+		return "latest"
+	case strings.HasSuffix(path, "/events"):
+		return "events"
+	case path == "/metrics", path == "/healthz", path == "/readyz", path == "/healthz/deep", path == "/version":
+		return strings.TrimPrefix(path, "/")
+	case strings.HasPrefix(path, grpcServicePath):
+		return "grpc"
+	}
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	switch first {
+	case "admin", "hooks", "rebuilds", "search", "batch.json", "schemas", "stats", "transparency":
+		return strings.TrimSuffix(first, ".json")
+	}
+	return "channel"
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets streaming handlers flush through the recorder.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		requestDurations.Observe(endpointLabel(r.URL.Path), time.Since(started))
+		responsesByCode.Add(strconv.Itoa(rec.status), 1)
+	})
+}
+
+func writeExpvarMap(w io.Writer, m *expvar.Map, name, typ, help string, labels func(key string) string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	m.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels(kv.Key), kv.Value.String())
+	})
+}
+
+func cacheStatsByName() map[string]cacheStats {
+	stats := make(map[string]cacheStats, len(namedCaches))
+	for name, c := range namedCaches {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+func init() {
+	expvar.Publish("caches", expvar.Func(func() any { return cacheStatsByName() }))
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeExpvarMap(w, requestsByChannel, "selene_channel_requests_total", "counter", "Update checks per channel.", func(key string) string {
+		product, branch, _ := strings.Cut(key, "/")
+		return fmt.Sprintf("product=%q,branch=%q", product, branch)
+	})
+	writeExpvarMap(w, responsesByCode, "selene_http_responses_total", "counter", "HTTP responses by status code.", func(key string) string {
+		return fmt.Sprintf("code=%q", key)
+	})
+	requestDurations.write(w, "selene_http_request_duration_seconds", "endpoint", "HTTP request latency by endpoint.")
+	upstreamDurations.write(w, "selene_upstream_request_duration_seconds", "outcome", "Nexus request duration by outcome.")
+	writeExpvarMap(w, failuresByClass, "selene_failures_total", "counter", "Failed requests by failure class.", func(key string) string {
+		return fmt.Sprintf("class=%q", key)
+	})
+	writeExpvarMap(w, cacheLookups, "selene_cache_lookups_total", "counter", "Cache lookups by cache and result.", func(key string) string {
+		cache, result, _ := strings.Cut(key, ":")
+		return fmt.Sprintf("cache=%q,result=%q", cache, result)
+	})
+	caches := cacheStatsByName()
+	names := slices.Sorted(maps.Keys(caches))
+	fmt.Fprintf(w, "# HELP selene_cache_entries Entries currently held per cache.\n# TYPE selene_cache_entries gauge\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "selene_cache_entries{cache=%q} %d\n", name, caches[name].Entries)
+	}
+	fmt.Fprintf(w, "# HELP selene_cache_bytes Approximate memory held per cache.\n# TYPE selene_cache_bytes gauge\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "selene_cache_bytes{cache=%q} %d\n", name, caches[name].Bytes)
+	}
+	fmt.Fprintf(w, "# HELP selene_cache_evictions_total Entries evicted to stay within cache limits.\n# TYPE selene_cache_evictions_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "selene_cache_evictions_total{cache=%q} %d\n", name, caches[name].Evictions)
+	}
+	fmt.Fprintf(w, "# HELP selene_upstream_requests_total Requests sent to Nexus.\n# TYPE selene_upstream_requests_total counter\nselene_upstream_requests_total %d\n", upstreamRequests.Value())
+	fmt.Fprintf(w, "# HELP selene_upstream_budget_rejected_total Nexus requests refused by the request budget.\n# TYPE selene_upstream_budget_rejected_total counter\nselene_upstream_budget_rejected_total %d\n", upstreamBudgetRejected.Value())
+	fmt.Fprintf(w, "# HELP selene_clock_drift_seconds Measured offset from the NTP server.\n# TYPE selene_clock_drift_seconds gauge\nselene_clock_drift_seconds %g\n", clockDrift.Value())
+	fmt.Fprintf(w, "# HELP selene_goroutines Goroutines currently running.\n# TYPE selene_goroutines gauge\nselene_goroutines %d\n", runtime.NumGoroutine())
+	if files, err := openFileCount(); err == nil {
+		fmt.Fprintf(w, "# HELP selene_open_files File descriptors currently open.\n# TYPE selene_open_files gauge\nselene_open_files %d\n", files)
+	}
+	readyValue := 0
+	if ready.Load() {
+		readyValue = 1
+	}
+	fmt.Fprintf(w, "# HELP selene_ready Whether warmup has completed.\n# TYPE selene_ready gauge\nselene_ready %d\n", readyValue)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// runMigrate checks that every version of every channel resolves at a new
+// public base URL, after a Nexus migration or when the filesystem backend's
+// files move to another host. Each version is re-resolved from the current
+// backend, its URLs are rewritten to the new base and every file is
+// downloaded from there and checked against its recorded checksum. Without
+// -dry-run, the manifest snapshot, which channels fall back to while the
+// backend is down, is then rewritten to the new URLs for every channel whose
+// served version resolves. It runs with the server stopped, before nexus.url
+// or filesystem.publicUrl is switched over, so a running server cannot write
+// back manifests pointing at the old location.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report what would be migrated")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: selene-update-server -config config.json migrate [-dry-run] <new public base url>")
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("Missing base URL")
+	}
+	if config().Mirror != nil {
+		return fmt.Errorf("A mirror serves the URLs of its primary, migrate the primary instead")
+	}
+	from := publicRepositoryUrl()
+	if releaseStore != nil {
+		from = strings.TrimSuffix(config().Filesystem.PublicUrl, "/") + "/"
+	}
+	to := strings.TrimSuffix(fs.Arg(0), "/") + "/"
+
+	// problems holds what failed per "group:artifact:version", so a version
+	// listed by several channels is only downloaded once.
+	problems := make(map[string][]string)
+	var failedVersions, failedChannels int
+	for _, ch := range allChannels() {
+		versions, err := channelVersions(ch)
+		if err != nil {
+			log.Printf("%s: failed to list versions: %v", ch.Key(), err)
+			failedChannels++
+			continue
+		}
+		for _, version := range versions {
+			key := ch.Group + ":" + ch.Artifact + ":" + version
+			if _, ok := problems[key]; ok {
+				continue
+			}
+			manifest, err := releaseManifest(ch, version)
+			if err != nil {
+				problems[key] = []string{err.Error()}
+			} else {
+				_, problems[key] = migrateManifest(manifest, from, to)
+			}
+			for _, problem := range problems[key] {
+				log.Printf("%s %s: %s", ch.Key(), version, problem)
+			}
+			if len(problems[key]) > 0 {
+				failedVersions++
+			} else {
+				log.Printf("%s %s: resolves at %s", ch.Key(), version, to)
+			}
+		}
+	}
+	log.Printf("Checked %d versions at %s, %d failed", len(problems), to, failedVersions)
+
+	if config().SnapshotPath != "" {
+		var migrated int
+		for _, ch := range allChannels() {
+			key := ch.Key()
+			resp, ok := lastServed.Get(key)
+			if !ok {
+				continue
+			}
+			rewritten := rewriteManifest(resp, from, to)
+			if failed, checked := problems[ch.Group+":"+ch.Artifact+":"+resp.Version]; !checked {
+				// The served version is no longer listed, as it was yanked
+				// or deleted, so check the snapshot's own files.
+				var snapshotProblems []string
+				rewritten, snapshotProblems = migrateManifest(resp, from, to)
+				for _, problem := range snapshotProblems {
+					log.Printf("%s %s: %s", key, resp.Version, problem)
+				}
+				if len(snapshotProblems) > 0 {
+					failedVersions++
+					continue
+				}
+			} else if len(failed) > 0 {
+				continue
+			}
+			if !*dryRun {
+				if _, err := lastServed.Update(key, rewritten); err != nil {
+					return fmt.Errorf("Failed to write manifest snapshot: %w", err)
+				}
+			}
+			migrated++
+		}
+		log.Printf("Migrated the snapshot of %d channels from %s to %s", migrated, from, to)
+	}
+	if *dryRun {
+		log.Printf("Dry run, the snapshot was not changed")
+	}
+	if failedChannels > 0 {
+		return fmt.Errorf("Failed to list the versions of %d channels", failedChannels)
+	}
+	if failedVersions > 0 {
+		return fmt.Errorf("%d versions do not resolve at %s", failedVersions, to)
+	}
+	return nil
+}
+
+func rewriteRepositoryUrl(url, from, to string) string {
+	if rest, ok := strings.CutPrefix(url, from); ok {
+		return to + rest
+	}
+	return url
+}
+
+// verifyDownload fetches url and compares it to the expected SHA-256, if
+// one is known.
+func verifyDownload(url, expected string) error {
+	resp, err := downloadDo(bundleClient, http.MethodGet, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Downloading returned %s", resp.Status)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return err
+	}
+	if expected != "" && hex.EncodeToString(hash.Sum(nil)) != expected {
+		return fmt.Errorf("Checksum mismatch")
+	}
+	return nil
+}
+
+// rewriteManifest points a manifest's URLs at another base.
+func rewriteManifest(resp UpdaterResponse, from, to string) UpdaterResponse {
+	rewritten := resp
+	rewritten.Url = rewriteRepositoryUrl(resp.Url, from, to)
+	rewritten.Libraries = make(map[string]string, len(resp.Libraries))
+	for fileName, url := range resp.Libraries {
+		rewritten.Libraries[fileName] = rewriteRepositoryUrl(url, from, to)
+	}
+	if resp.Assets != nil {
+		rewritten.Assets = make(map[string]string, len(resp.Assets))
+		for role, url := range resp.Assets {
+			rewritten.Assets[role] = rewriteRepositoryUrl(url, from, to)
+		}
+	}
+	return rewritten
+}
+
+// migrateManifest rewrites a manifest's URLs from one base to another and
+// checks every rewritten file, returning what does not resolve.
+func migrateManifest(resp UpdaterResponse, from, to string) (UpdaterResponse, []string) {
+	migrated := rewriteManifest(resp, from, to)
+	var problems []string
+	check := func(url, expected string) {
+		if err := verifyDownload(url, expected); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", url, err))
+		}
+	}
+	check(migrated.Url, resp.Sha256)
+	for fileName, url := range migrated.Libraries {
+		check(url, resp.LibrarySha256[fileName])
+	}
+	for _, url := range migrated.Assets {
+		check(url, "")
+	}
+	return migrated, problems
+}
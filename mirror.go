@@ -0,0 +1,286 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MirrorConfig turns the server into a read-only replica of a primary update
+// server. Manifests are synced from the primary's batch API instead of
+// Nexus; with Artifacts set, the jar and libraries are downloaded too and
+// served from /files/ under PublicUrl. With PublicKey set, manifests come
+// from the primary's signed sync feed instead and are verified against it.
+type MirrorConfig struct {
+	Primary             string `json:"primary" required:"true"`
+	SyncIntervalSeconds int    `json:"syncIntervalSeconds,omitempty"`
+	Artifacts           bool   `json:"artifacts,omitempty"`
+	ArtifactsPath       string `json:"artifactsPath,omitempty"`
+	PublicUrl           string `json:"publicUrl,omitempty"`
+	PublicKey           string `json:"publicKey,omitempty"`
+}
+
+const defaultMirrorSyncInterval = time.Minute
+
+var mirrorClient = &http.Client{Timeout: 30 * time.Second}
+
+var mirroredBytes = expvar.NewInt("mirrored_bytes")
+
+var errMirrorNotSynced = fmt.Errorf("%w: channel not synced from primary yet", errNoRelease)
+
+type mirrorSync struct {
+	cfg     MirrorConfig
+	primary *url.URL
+	proxy   *httputil.ReverseProxy
+	feed    *syncFeed
+
+	mu        sync.RWMutex
+	manifests map[string]UpdaterResponse
+	lastSync  time.Time
+	lastError error
+	applied   int64
+}
+
+// mirror is nil unless the server runs as a mirror.
+var mirror *mirrorSync
+
+func newMirrorSync(cfg MirrorConfig) (*mirrorSync, error) {
+	primary, err := url.Parse(strings.TrimSuffix(cfg.Primary, "/"))
+	if err != nil || primary.Scheme == "" || primary.Host == "" {
+		return nil, fmt.Errorf("Invalid mirror primary %q", cfg.Primary)
+	}
+	if cfg.Artifacts && (cfg.ArtifactsPath == "" || cfg.PublicUrl == "") {
+		return nil, fmt.Errorf("Mirroring artifacts requires artifactsPath and publicUrl")
+	}
+	if cfg.SyncIntervalSeconds <= 0 {
+		cfg.SyncIntervalSeconds = int(defaultMirrorSyncInterval.Seconds())
+	}
+	return &mirrorSync{
+		cfg:       cfg,
+		primary:   primary,
+		proxy:     httputil.NewSingleHostReverseProxy(primary),
+		manifests: make(map[string]UpdaterResponse),
+	}, nil
+}
+
+// Run keeps the mirror in sync; the first sync happens during warmup.
+func (m *mirrorSync) Run() {
+	for {
+		time.Sleep(time.Duration(m.cfg.SyncIntervalSeconds) * time.Second)
+		if err := m.Sync(); err != nil {
+			log.Printf("Warning: mirror sync failed: %v", err)
+		}
+	}
+}
+
+// Sync fetches every public channel from the primary in one batch request.
+// Channels that fail on the primary keep their previous manifest.
+func (m *mirrorSync) Sync() error {
+	var keys []string
+	for _, ch := range allChannels() {
+		if !ch.Private {
+			keys = append(keys, ch.Key())
+		}
+	}
+	var err error
+	if m.feed != nil {
+		err = m.syncFeed()
+	} else {
+		err = m.sync(keys)
+	}
+	m.mu.Lock()
+	m.lastError = err
+	if err == nil {
+		m.lastSync = clock.Now()
+	}
+	m.mu.Unlock()
+	return err
+}
+
+func (m *mirrorSync) sync(keys []string) error {
+	for len(keys) > 0 {
+		n := min(len(keys), maxBatchChannels)
+		batch, err := m.fetchBatch(keys[:n])
+		if err != nil {
+			return err
+		}
+		for key, message := range batch.Errors {
+			log.Printf("Warning: primary failed to resolve %s: %s", key, message)
+		}
+		for key, manifest := range batch.Manifests {
+			if m.cfg.Artifacts {
+				if manifest, err = m.mirrorArtifacts(manifest); err != nil {
+					log.Printf("Warning: failed to mirror artifacts of %s %s: %v", key, manifest.Version, err)
+					continue
+				}
+			}
+			m.mu.Lock()
+			m.manifests[key] = manifest
+			m.mu.Unlock()
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// syncFeed pulls new entries from the primary's sync feed, verifies them
+// and applies every entry not applied yet, including ones loaded from disk.
+func (m *mirrorSync) syncFeed() error {
+	for {
+		var page syncPage
+		if err := m.getJson(fmt.Sprintf("/sync/feed?after=%d", m.feed.LastSeq()), &page); err != nil {
+			return err
+		}
+		if err := m.feed.Accept(page.Entries); err != nil {
+			return fmt.Errorf("Rejected sync feed from primary: %w", err)
+		}
+		if len(page.Entries) == 0 || m.feed.LastSeq() >= page.LastSeq {
+			break
+		}
+	}
+	for {
+		entries := m.feed.Since(m.applied, maxSyncPageSize)
+		if len(entries) == 0 {
+			return nil
+		}
+		for _, e := range entries {
+			var manifest UpdaterResponse
+			if err := json.Unmarshal(e.Manifest, &manifest); err != nil {
+				return fmt.Errorf("Invalid manifest in sync entry %d: %w", e.Seq, err)
+			}
+			var err error
+			if m.cfg.Artifacts {
+				if manifest, err = m.mirrorArtifacts(manifest); err != nil {
+					return fmt.Errorf("Failed to mirror artifacts of %s %s: %w", e.Channel, manifest.Version, err)
+				}
+			}
+			m.mu.Lock()
+			m.manifests[e.Channel] = manifest
+			m.applied = e.Seq
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *mirrorSync) getJson(path string, v any) error {
+	resp, err := mirrorClient.Get(m.primary.String() + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Primary returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (m *mirrorSync) fetchBatch(keys []string) (batchResponse, error) {
+	var batch batchResponse
+	err := m.getJson("/batch.json?channels="+url.QueryEscape(strings.Join(keys, ",")), &batch)
+	return batch, err
+}
+
+// Resolve returns the mirrored manifest for a channel; it never contacts
+// Nexus.
+func (m *mirrorSync) Resolve(ch channel) (UpdaterResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	resp, ok := m.manifests[ch.Key()]
+	if !ok {
+		return UpdaterResponse{}, errMirrorNotSynced
+	}
+	return resp, nil
+}
+
+func (m *mirrorSync) Status() map[string]any {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := map[string]any{"primary": m.primary.String(), "channels": len(m.manifests)}
+	if m.feed != nil {
+		status["appliedSeq"] = m.applied
+	}
+	if !m.lastSync.IsZero() {
+		status["lastSync"] = m.lastSync
+	}
+	if m.lastError != nil {
+		status["lastError"] = m.lastError.Error()
+	}
+	return status
+}
+
+// mirrorArtifacts downloads the jar and libraries of a manifest and points
+// its URLs at this mirror. Files already on disk are reused.
+func (m *mirrorSync) mirrorArtifacts(resp UpdaterResponse) (UpdaterResponse, error) {
+	jarUrl, err := m.download(resp.Url, resp.Sha256)
+	if err != nil {
+		return resp, err
+	}
+	resp.Url = jarUrl
+	libraries := make(map[string]string, len(resp.Libraries))
+	for fileName, libUrl := range resp.Libraries {
+		if libraries[fileName], err = m.download(libUrl, resp.LibrarySha256[fileName]); err != nil {
+			return resp, err
+		}
+	}
+	resp.Libraries = libraries
+	return resp, nil
+}
+
+func (m *mirrorSync) download(fileUrl, sha256sum string) (string, error) {
+	u, err := url.Parse(fileUrl)
+	if err != nil {
+		return "", err
+	}
+	rel := path.Clean("/" + u.Path)
+	local := filepath.Join(m.cfg.ArtifactsPath, filepath.FromSlash(rel))
+	mirrored := strings.TrimSuffix(m.cfg.PublicUrl, "/") + "/files" + rel
+	if _, err := os.Stat(local); err == nil {
+		return mirrored, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return "", err
+	}
+	resp, err := http.Get(fileUrl)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Downloading %s returned %s", fileUrl, resp.Status)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(local), filepath.Base(local)+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	mirroredBytes.Add(n)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if sha256sum != "" && hex.EncodeToString(hash.Sum(nil)) != sha256sum {
+		return "", fmt.Errorf("Checksum mismatch for %s", fileUrl)
+	}
+	return mirrored, os.Rename(tmp.Name(), local)
+}
+
+func (m *mirrorSync) FileServer() http.Handler {
+	return http.StripPrefix("/files", http.FileServer(noListingFs{http.Dir(m.cfg.ArtifactsPath)}))
+}
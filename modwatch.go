@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// stableBranch is where releases end up; a release on any other branch is
+// pending and may still change before it reaches stable.
+const stableBranch = "stable"
+
+// ModWatch is a mod author's registration of the client libraries their
+// mod depends on, as "group:name" (any classifier) or
+// "group:name:classifier", and where to tell them when a pending release
+// changes one. Owner is the admin caller who registered it, set by the
+// server.
+type ModWatch struct {
+	Product   string         `json:"product"`
+	Libraries []string       `json:"libraries"`
+	Notifier  NotifierConfig `json:"notifier"`
+	Owner     string         `json:"owner,omitempty"`
+}
+
+// modWatchSummary is what the admin API lists, leaving out notifier URLs
+// and credentials.
+type modWatchSummary struct {
+	Product   string   `json:"product"`
+	Libraries []string `json:"libraries"`
+	Owner     string   `json:"owner,omitempty"`
+}
+
+// modNotifierTypes are the notifier types mod authors may point at their
+// own endpoint, on one of the hosts in modNotifierHosts. Anything else,
+// such as email through the server's SMTP account, is left to notifiers
+// the operator configured.
+var modNotifierTypes = []string{"discord", "slack", "webhook", "ntfy", "matrix"}
+
+type modWatchStore struct {
+	mu      sync.RWMutex
+	path    string
+	watches map[string]ModWatch
+	// notified remembers "mod@version" so a version seen on several
+	// pending branches only warns once.
+	notified map[string]bool
+}
+
+func newModWatchStore(path string) *modWatchStore {
+	return &modWatchStore{path: path, watches: make(map[string]ModWatch), notified: make(map[string]bool)}
+}
+
+var modWatches = newModWatchStore("")
+
+func (s *modWatchStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.watches)
+}
+
+func (s *modWatchStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJsonFile(s.path, s.watches)
+}
+
+var (
+	errModNotWatched = errors.New("Mod is not registered")
+	errModOwned      = errors.New("Mod is registered by someone else")
+)
+
+// validateModWatch checks a registration. Unless trusted, its notifier
+// must be one the operator configured or an https endpoint on one of
+// modNotifierHosts, so mod authors cannot make the server post to
+// arbitrary, possibly internal, addresses.
+func validateModWatch(mod string, watch ModWatch, trusted bool) error {
+	if mod == "" || len(watch.Libraries) == 0 {
+		return fmt.Errorf("Missing mod or libraries")
+	}
+	if _, err := artifacts.Lookup(watch.Product); err != nil {
+		return fmt.Errorf("Unknown product %q", watch.Product)
+	}
+	if !trusted && watch.Notifier.Type != "" {
+		if !slices.Contains(modNotifierTypes, watch.Notifier.Type) {
+			return fmt.Errorf("Notifier type %q is not available to mod authors, use a configured notifier by name", watch.Notifier.Type)
+		}
+		u, err := url.Parse(watch.Notifier.Url)
+		if err != nil || u.Scheme != "https" || !slices.Contains(config().ModNotifierHosts, u.Hostname()) {
+			return fmt.Errorf("Notifier URL must be https on one of modNotifierHosts")
+		}
+	}
+	_, err := newModNotifier(watch.Notifier)
+	return err
+}
+
+// newModNotifier builds a registration's notifier. One with only a name
+// refers to the notifier of that name in the config.
+func newModNotifier(cfg NotifierConfig) (Notifier, error) {
+	if cfg.Type != "" {
+		return newNotifier(cfg)
+	}
+	for _, n := range config().Notifiers {
+		if n.Name != "" && n.Name == cfg.Name {
+			return newNotifier(n)
+		}
+	}
+	return nil, fmt.Errorf("Unknown notifier %q", cfg.Name)
+}
+
+// Watch registers or replaces a mod's dependencies. A mod registered by
+// someone else can only be replaced when manageAny is set. The change
+// applies in memory even if it cannot be persisted.
+func (s *modWatchStore) Watch(mod string, watch ModWatch, manageAny bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.watches[mod]; ok && existing.Owner != watch.Owner && !manageAny {
+		return errModOwned
+	}
+	s.watches[mod] = watch
+	return s.persist()
+}
+
+func (s *modWatchStore) Unwatch(mod, owner string, manageAny bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.watches[mod]
+	if !ok {
+		return errModNotWatched
+	}
+	if existing.Owner != owner && !manageAny {
+		return errModOwned
+	}
+	delete(s.watches, mod)
+	return s.persist()
+}
+
+func (s *modWatchStore) List() map[string]modWatchSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make(map[string]modWatchSummary, len(s.watches))
+	for mod, watch := range s.watches {
+		list[mod] = modWatchSummary{Product: watch.Product, Libraries: watch.Libraries, Owner: watch.Owner}
+	}
+	return list
+}
+
+// affected returns the watches of product hit by changes, each with the
+// changes that concern it, and marks them notified for version.
+func (s *modWatchStore) affected(product, version string, changes libraryChanges) map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hits := make(map[string][]string)
+	for mod, watch := range s.watches {
+		if watch.Product != product || s.notified[mod+"@"+version] {
+			continue
+		}
+		for _, c := range slices.Concat(changes.Added, changes.Upgraded, changes.Removed) {
+			if slices.ContainsFunc(watch.Libraries, func(lib string) bool {
+				return c.Library == lib || strings.HasPrefix(c.Library, lib+":")
+			}) {
+				hits[mod] = append(hits[mod], describeLibraryChange(c))
+			}
+		}
+		if len(hits[mod]) > 0 {
+			s.notified[mod+"@"+version] = true
+		}
+	}
+	return hits
+}
+
+func (s *modWatchStore) notifier(mod string) (Notifier, error) {
+	s.mu.RLock()
+	watch, ok := s.watches[mod]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errModNotWatched
+	}
+	return newModNotifier(watch.Notifier)
+}
+
+// warnModAuthors compares a pending release with what the stable channel
+// of the product serves and tells every mod author whose dependencies it
+// changes.
+func warnModAuthors(ch channel, manifest UpdaterResponse) error {
+	stable, ok := lastServed.Get(ch.Product + "/" + stableBranch)
+	if !ok || compareVersions(manifest.Version, stable.Version) <= 0 {
+		return nil
+	}
+	hits := modWatches.affected(ch.Product, manifest.Version, diffLibraries(stable, manifest))
+	var failed []string
+	for _, mod := range slices.Sorted(maps.Keys(hits)) {
+		n, err := modWatches.notifier(mod)
+		if err == nil {
+			reason := fmt.Sprintf("%s depends on libraries that change from stable %s: %s", mod, stable.Version, strings.Join(hits[mod], "; "))
+			err = deliver(n, notification{Type: EventDependencyChanged, Channel: ch.Key(), Version: manifest.Version, Reason: reason})
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", mod, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("Failed to warn mod authors: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func subscribeModWatches(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type != EventReleaseDetected || e.Manifest == nil {
+			return
+		}
+		// Mod authors' endpoints must not learn about private channels,
+		// just like the operator's notifiers.
+		ch, err := parseChannelKey(e.Channel)
+		if err != nil || ch.Branch == stableBranch || ch.Private {
+			return
+		}
+		manifest := *e.Manifest
+		jobs.Submit("mod-watches", func() error {
+			return warnModAuthors(ch, manifest)
+		})
+	})
+}
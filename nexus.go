@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type NexusConfig struct {
+	Url string `json:"url"`
+	// PublicRepository is the repository launchers download from.
+	PublicRepository string `json:"publicRepository"`
+	// InternalRepositories are rewritten to PublicRepository in download URLs.
+	InternalRepositories []string `json:"internalRepositories"`
+}
+
+func nexusSearchUrl() string {
+	return strings.TrimSuffix(config().Nexus.Url, "/") + "/service/rest/v1/search"
+}
+
+func publicRepositoryUrl() string {
+	return strings.TrimSuffix(config().Nexus.Url, "/") + "/repository/" + config().Nexus.PublicRepository + "/"
+}
+
+type nexusAsset struct {
+	DownloadUrl  string `json:"downloadUrl"`
+	LastModified string `json:"lastModified,omitempty"`
+	Checksum     struct {
+		Sha1   string `json:"sha1,omitempty"`
+		Sha256 string `json:"sha256,omitempty"`
+	} `json:"checksum"`
+	Maven2 struct {
+		Classifier string `json:"classifier,omitempty"`
+		Extension  string `json:"extension,omitempty"`
+	} `json:"maven2"`
+}
+
+type nexusItem struct {
+	Version string       `json:"version"`
+	Assets  []nexusAsset `json:"assets"`
+}
+
+func (item nexusItem) findAsset(classifier, extension string) (nexusAsset, bool) {
+	for _, asset := range item.Assets {
+		if asset.Maven2.Classifier == classifier && asset.Maven2.Extension == extension {
+			return asset, true
+		}
+	}
+	return nexusAsset{}, false
+}
+
+type nexusSearchPage struct {
+	Items             []nexusItem `json:"items"`
+	ContinuationToken string      `json:"continuationToken"`
+}
+
+type nexusStatusError struct {
+	Status     string
+	StatusCode int
+}
+
+func (e *nexusStatusError) Error() string {
+	return fmt.Sprintf("Nexus API error: %s", e.Status)
+}
+
+func searchNexus(repo, group, artifact, version, continuationToken string) (nexusSearchPage, error) {
+	query := url.Values{}
+	query.Set("repository", repo)
+	query.Set("group", group)
+	query.Set("name", artifact)
+	query.Set("sort", "version")
+	if version != "" {
+		query.Set("version", version)
+	}
+	if continuationToken != "" {
+		query.Set("continuationToken", continuationToken)
+	}
+
+	var page nexusSearchPage
+	resp, err := upstreamGet(nexusSearchUrl() + "?" + query.Encode())
+	if err != nil {
+		return page, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return page, &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+func listNexusVersions(repo, group, artifact string) ([]nexusItem, error) {
+	var items []nexusItem
+	continuationToken := ""
+	for {
+		page, err := searchNexus(repo, group, artifact, "", continuationToken)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.ContinuationToken == "" {
+			return items, nil
+		}
+		continuationToken = page.ContinuationToken
+	}
+}
+
+func findNexusVersion(repo, group, artifact, version string) (nexusItem, error) {
+	page, err := searchNexus(repo, group, artifact, version, "")
+	if err != nil {
+		return nexusItem{}, err
+	}
+	for _, item := range page.Items {
+		if item.Version == version {
+			return item, nil
+		}
+	}
+	return nexusItem{}, &nexusStatusError{Status: "404 Version Not Found", StatusCode: http.StatusNotFound}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Notifier interface {
+	OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error
+	OnResolutionFailure(channel string, err error) error
+	OnRolloutHalted(channel, version, reason string) error
+	OnTrafficAnomaly(channel, reason string) error
+	OnResourceAlert(reason string) error
+	OnSizeRegression(channel, version, reason string) error
+	OnDependencyChanged(channel, version, reason string) error
+}
+
+type NotifierConfig struct {
+	Type         string   `json:"type" required:"true"`
+	Name         string   `json:"name,omitempty"`
+	Url          string   `json:"url,omitempty"`
+	SmtpHost     string   `json:"smtpHost,omitempty"`
+	SmtpPort     int      `json:"smtpPort,omitempty"`
+	Username     string   `json:"username,omitempty"`
+	Password     string   `json:"password,omitempty"`
+	AccessToken  string   `json:"accessToken,omitempty"`
+	Rooms        []string `json:"rooms,omitempty"`
+	BodyTemplate string   `json:"bodyTemplate,omitempty"`
+	ContentType  string   `json:"contentType,omitempty"`
+	From         string   `json:"from,omitempty"`
+	To           []string `json:"to,omitempty"`
+
+	Templates map[string]EmailTemplateConfig `json:"templates,omitempty"`
+}
+
+func newNotifier(cfg NotifierConfig) (Notifier, error) {
+	switch cfg.Type {
+	case "discord":
+		return &chatNotifier{url: cfg.Url, field: "content", release: discordReleaseMessage}, nil
+	case "slack":
+		return &chatNotifier{url: cfg.Url, field: "text", release: slackReleaseMessage}, nil
+	case "webhook":
+		if cfg.BodyTemplate != "" {
+			return newTemplatedWebhookNotifier(cfg)
+		}
+		return &webhookNotifier{url: cfg.Url}, nil
+	case "ntfy":
+		return &ntfyNotifier{url: cfg.Url, accessToken: cfg.AccessToken}, nil
+	case "email":
+		return newEmailNotifier(cfg)
+	case "matrix":
+		return newMatrixNotifier(cfg)
+	default:
+		return nil, fmt.Errorf("Unknown notifier type %q", cfg.Type)
+	}
+}
+
+func postJson(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func newReleaseMessage(channel string, manifest UpdaterResponse, previousVersion string) string {
+	msg := releaseTitle(channel, manifest)
+	if previousVersion != "" {
+		msg += fmt.Sprintf(" (previously %s)", previousVersion)
+	}
+	return msg + "\n" + manifest.Url
+}
+
+type chatNotifier struct {
+	url     string
+	field   string
+	release func(channel string, manifest UpdaterResponse, previousVersion string) any
+}
+
+func (n *chatNotifier) send(text string) error {
+	return postJson(n.url, map[string]string{n.field: text})
+}
+
+func (n *chatNotifier) OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error {
+	return postJson(n.url, n.release(channel, manifest, previousVersion))
+}
+
+func (n *chatNotifier) OnResolutionFailure(channel string, err error) error {
+	return n.send(fmt.Sprintf("Failed to resolve latest version for %s: %v", channel, err))
+}
+
+func (n *chatNotifier) OnRolloutHalted(channel, version, reason string) error {
+	return n.send(fmt.Sprintf("Rollout of %s on %s halted: %s", version, channel, reason))
+}
+
+func (n *chatNotifier) OnTrafficAnomaly(channel, reason string) error {
+	return n.send(fmt.Sprintf("Unusual traffic on %s: %s", channel, reason))
+}
+
+func (n *chatNotifier) OnResourceAlert(reason string) error {
+	return n.send("Update server resource alert: " + reason)
+}
+
+func (n *chatNotifier) OnSizeRegression(channel, version, reason string) error {
+	return n.send(fmt.Sprintf("Download size regression in %s %s: %s", channel, version, reason))
+}
+
+func (n *chatNotifier) OnDependencyChanged(channel, version, reason string) error {
+	return n.send(fmt.Sprintf("Pending release %s on %s changes dependencies: %s", version, channel, reason))
+}
+
+type webhookNotifier struct {
+	url string
+}
+
+type webhookPayload struct {
+	Event           string           `json:"event"`
+	Channel         string           `json:"channel"`
+	Version         string           `json:"version,omitempty"`
+	PreviousVersion string           `json:"previousVersion,omitempty"`
+	Reason          string           `json:"reason,omitempty"`
+	Manifest        *UpdaterResponse `json:"manifest,omitempty"`
+}
+
+func (n *webhookNotifier) OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error {
+	return postJson(n.url, webhookPayload{Event: "new_release", Channel: channel, Version: manifest.Version, PreviousVersion: previousVersion, Manifest: &manifest})
+}
+
+func (n *webhookNotifier) OnResolutionFailure(channel string, err error) error {
+	return postJson(n.url, webhookPayload{Event: "resolution_failure", Channel: channel, Reason: err.Error()})
+}
+
+func (n *webhookNotifier) OnRolloutHalted(channel, version, reason string) error {
+	return postJson(n.url, webhookPayload{Event: "rollout_halted", Channel: channel, Version: version, Reason: reason})
+}
+
+func (n *webhookNotifier) OnTrafficAnomaly(channel, reason string) error {
+	return postJson(n.url, webhookPayload{Event: "traffic_anomaly", Channel: channel, Reason: reason})
+}
+
+func (n *webhookNotifier) OnResourceAlert(reason string) error {
+	return postJson(n.url, webhookPayload{Event: "resource_alert", Reason: reason})
+}
+
+func (n *webhookNotifier) OnSizeRegression(channel, version, reason string) error {
+	return postJson(n.url, webhookPayload{Event: "size_regression", Channel: channel, Version: version, Reason: reason})
+}
+
+func (n *webhookNotifier) OnDependencyChanged(channel, version, reason string) error {
+	return postJson(n.url, webhookPayload{Event: "dependency_changed", Channel: channel, Version: version, Reason: reason})
+}
+
+const failureNotifyInterval = 15 * time.Minute
+
+type notificationTarget struct {
+	name     string
+	kind     string
+	notifier Notifier
+
+	mu     sync.Mutex
+	status notifierDeliveryStatus
+}
+
+type notificationDispatcher struct {
+	targets     []*notificationTarget
+	deadLetters *deadLetterStore
+
+	mu           sync.Mutex
+	lastFailures map[string]time.Time
+}
+
+func newNotificationDispatcher(configs []NotifierConfig, deadLetters *deadLetterStore) (*notificationDispatcher, error) {
+	d := &notificationDispatcher{deadLetters: deadLetters, lastFailures: make(map[string]time.Time)}
+	for i, cfg := range configs {
+		n, err := newNotifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+		name := cfg.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", cfg.Type, i)
+		}
+		d.targets = append(d.targets, &notificationTarget{name: name, kind: cfg.Type, notifier: n})
+	}
+	return d, nil
+}
+
+var notifications = &notificationDispatcher{deadLetters: newDeadLetterStore(""), lastFailures: make(map[string]time.Time)}
+
+func (d *notificationDispatcher) each(msg notification) {
+	for _, t := range d.targets {
+		d.attempt(t, msg, 1)
+	}
+}
+
+// attempt queues one delivery of msg to t. Failures are retried with
+// exponential backoff and end up in the dead-letter store once
+// maxDeliveryAttempts is exhausted.
+func (d *notificationDispatcher) attempt(t *notificationTarget, msg notification, attempt int) {
+	failed := func(err error) {
+		t.mu.Lock()
+		t.status.LastError = err.Error()
+		t.status.LastErrorAt = clock.Now()
+		if attempt < maxDeliveryAttempts {
+			t.status.Retried++
+		} else {
+			t.status.DeadLettered++
+		}
+		t.mu.Unlock()
+		if attempt < maxDeliveryAttempts {
+			time.AfterFunc(deliveryBackoffFor(attempt), func() { d.attempt(t, msg, attempt+1) })
+			return
+		}
+		log.Printf("Warning: giving up on %s notification to %s after %d attempts: %v", msg.Type, t.name, attempt, err)
+		d.deadLetters.Add(deadLetter{Id: newDeadLetterId(), Notifier: t.name, Notification: msg, Attempts: attempt, LastError: err.Error(), FailedAt: clock.Now()})
+	}
+	queued := jobs.Submit("notify", func() error {
+		if err := deliver(t.notifier, msg); err != nil {
+			failed(err)
+			return err
+		}
+		t.mu.Lock()
+		t.status.Delivered++
+		t.status.LastDeliveredAt = clock.Now()
+		t.mu.Unlock()
+		return nil
+	})
+	if !queued {
+		failed(fmt.Errorf("Job queue full"))
+	}
+}
+
+// Redeliver takes a dead letter out of the store and delivers it again with a
+// fresh set of attempts.
+func (d *notificationDispatcher) Redeliver(id string) error {
+	letter, ok := d.deadLetters.Take(id)
+	if !ok {
+		return fmt.Errorf("Unknown dead letter %q", id)
+	}
+	i := slices.IndexFunc(d.targets, func(t *notificationTarget) bool { return t.name == letter.Notifier })
+	if i < 0 {
+		d.deadLetters.Add(letter)
+		return fmt.Errorf("Notifier %s is no longer configured", letter.Notifier)
+	}
+	d.attempt(d.targets[i], letter.Notification, 1)
+	return nil
+}
+
+func (d *notificationDispatcher) Status() deliveryStatus {
+	status := deliveryStatus{Notifiers: []notifierDeliveryStatus{}, DeadLetters: d.deadLetters.List()}
+	for _, t := range d.targets {
+		t.mu.Lock()
+		s := t.status
+		t.mu.Unlock()
+		s.Name, s.Type = t.name, t.kind
+		status.Notifiers = append(status.Notifiers, s)
+	}
+	return status
+}
+
+func (d *notificationDispatcher) shouldNotifyFailure(channel string) bool {
+	return d.throttle(channel)
+}
+
+func (d *notificationDispatcher) throttle(channel string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.lastFailures[channel]; ok && time.Since(last) < failureNotifyInterval {
+		return false
+	}
+	d.lastFailures[channel] = time.Now()
+	return true
+}
+
+func (d *notificationDispatcher) Subscribe(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		// Notifiers post to shared rooms and webhooks, which must not
+		// learn about private channels.
+		if slices.Contains(config().PrivateChannels, e.Channel) {
+			return
+		}
+		msg := notification{Type: e.Type, Channel: e.Channel, Version: e.Version, PreviousVersion: e.PreviousVersion, Manifest: e.Manifest, Reason: e.Reason}
+		switch e.Type {
+		case EventReleaseDetected, EventRolloutHalted, EventResourceAlert, EventSizeRegression:
+			d.each(msg)
+		case EventResolutionFailed:
+			if d.shouldNotifyFailure(e.Channel) {
+				msg.Error = e.Err.Error()
+				d.each(msg)
+			}
+		case EventTrafficAnomaly:
+			if d.throttle("traffic:" + e.Channel) {
+				d.each(msg)
+			}
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release announcements go to the community's Discord or Slack channel, so
+// they are sent as a Discord embed or Slack blocks rather than plain text.
+// Slack still gets the plain text as the fallback for push notifications.
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Url         string              `json:"url,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+	Fields      []discordEmbedField `json:"fields"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+func releaseTitle(channel string, manifest UpdaterResponse) string {
+	return fmt.Sprintf("New Selene release on %s: %s", channel, manifest.Version)
+}
+
+// releaseColor is the channel's theme color as an RGB integer, or 0.
+func releaseColor(channel string) int {
+	ch, err := parseChannelKey(channel)
+	if err != nil {
+		return 0
+	}
+	theme := channelTheme(ch)
+	if theme == nil || theme.Color == "" {
+		return 0
+	}
+	hex := strings.TrimPrefix(theme.Color, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	color, _ := strconv.ParseInt(hex, 16, 32)
+	return int(color)
+}
+
+func releasePubDate(manifest UpdaterResponse) string {
+	if t, err := time.Parse(time.RFC3339, manifest.PubDate); err == nil {
+		return t.UTC().Format("2006-01-02 15:04 UTC")
+	}
+	return manifest.PubDate
+}
+
+func discordReleaseMessage(channel string, manifest UpdaterResponse, previousVersion string) any {
+	embed := discordEmbed{
+		Title:     releaseTitle(channel, manifest),
+		Url:       manifest.Url,
+		Color:     releaseColor(channel),
+		Timestamp: manifest.PubDate,
+		Fields: []discordEmbedField{
+			{Name: "Version", Value: manifest.Version, Inline: true},
+		},
+	}
+	if previousVersion != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Previous version", Value: previousVersion, Inline: true})
+	}
+	if manifest.PubDate != "" {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Published", Value: releasePubDate(manifest), Inline: true})
+	}
+	embed.Fields = append(embed.Fields, discordEmbedField{Name: "Download", Value: manifest.Url})
+	return discordMessage{Embeds: []discordEmbed{embed}}
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func slackReleaseMessage(channel string, manifest UpdaterResponse, previousVersion string) any {
+	fields := []slackText{{Type: "mrkdwn", Text: "*Version*\n" + manifest.Version}}
+	if previousVersion != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Previous version*\n" + previousVersion})
+	}
+	if manifest.PubDate != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: "*Published*\n" + releasePubDate(manifest)})
+	}
+	return slackMessage{
+		Text: newReleaseMessage(channel, manifest, previousVersion),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: releaseTitle(channel, manifest)}},
+			{Type: "section", Fields: fields},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("<%s|Download %s>", manifest.Url, manifest.FileName)}},
+		},
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type EmailTemplateConfig struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+var defaultEmailTemplates = map[string]EmailTemplateConfig{
+	"new_release": {
+		Subject: "Selene {{.Channel}} {{.Version}} released",
+		Body: `A new Selene release is available on the {{.Channel}} channel.
+
+Version: {{.Version}}{{if .PreviousVersion}} (previously {{.PreviousVersion}}){{end}}
+{{if .Manifest.PubDate}}Published: {{.Manifest.PubDate}}
+{{end}}Download: {{.Manifest.Url}}
+`,
+	},
+	"resolution_failure": {
+		Subject: "Selene {{.Channel}} resolution failure",
+		Body: `The update server failed to resolve the latest version for the {{.Channel}} channel.
+
+Error: {{.Error}}
+`,
+	},
+	"rollout_halted": {
+		Subject: "Selene {{.Channel}} rollout of {{.Version}} halted",
+		Body: `The rollout of {{.Version}} on the {{.Channel}} channel was halted.
+
+Reason: {{.Reason}}
+`,
+	},
+	"traffic_anomaly": {
+		Subject: "Selene {{.Channel}} unusual traffic",
+		Body: `Request volume on the {{.Channel}} channel deviates from its baseline.
+
+{{.Reason}}
+`,
+	},
+	"resource_alert": {
+		Subject: "Selene update server resource alert",
+		Body: `The update server's resource watchdog crossed a threshold, which may point to a leak.
+
+{{.Reason}}
+`,
+	},
+	"dependency_changed": {
+		Subject: "Selene {{.Channel}} {{.Version}} changes your mod's dependencies",
+		Body: `A pending Selene release on the {{.Channel}} channel changes libraries your mod depends on,
+before it reaches stable.
+
+{{.Reason}}
+`,
+	},
+	"size_regression": {
+		Subject: "Selene {{.Channel}} {{.Version}} download size regression",
+		Body: `The download of {{.Version}} on the {{.Channel}} channel is much larger than the previous release,
+which may mean debug symbols or duplicate natives were bundled by mistake.
+
+{{.Reason}}
+`,
+	},
+}
+
+type emailTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+type notificationTemplateData struct {
+	Channel         string
+	Version         string
+	PreviousVersion string
+	Manifest        UpdaterResponse
+	Error           string
+	Reason          string
+}
+
+type emailNotifier struct {
+	cfg       NotifierConfig
+	templates map[string]emailTemplate
+}
+
+func newEmailNotifier(cfg NotifierConfig) (*emailNotifier, error) {
+	n := &emailNotifier{cfg: cfg, templates: make(map[string]emailTemplate)}
+	for name, def := range defaultEmailTemplates {
+		tc := def
+		if override, ok := cfg.Templates[name]; ok {
+			if override.Subject != "" {
+				tc.Subject = override.Subject
+			}
+			if override.Body != "" {
+				tc.Body = override.Body
+			}
+		}
+		subject, err := template.New(name + ".subject").Parse(tc.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s subject template: %w", name, err)
+		}
+		body, err := template.New(name + ".body").Parse(tc.Body)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s body template: %w", name, err)
+		}
+		n.templates[name] = emailTemplate{subject: subject, body: body}
+	}
+	for name := range cfg.Templates {
+		if _, ok := defaultEmailTemplates[name]; !ok {
+			return nil, fmt.Errorf("Unknown email template %q", name)
+		}
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) send(name string, data notificationTemplateData) error {
+	tmpl := n.templates[name]
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", clock.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	addr := fmt.Sprintf("%s:%d", n.cfg.SmtpHost, n.cfg.SmtpPort)
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.SmtpHost)
+	}
+	return smtp.SendMail(addr, auth, n.cfg.From, n.cfg.To, msg.Bytes())
+}
+
+func (n *emailNotifier) OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error {
+	return n.send("new_release", notificationTemplateData{Channel: channel, Version: manifest.Version, PreviousVersion: previousVersion, Manifest: manifest})
+}
+
+func (n *emailNotifier) OnResolutionFailure(channel string, err error) error {
+	return n.send("resolution_failure", notificationTemplateData{Channel: channel, Error: err.Error()})
+}
+
+func (n *emailNotifier) OnRolloutHalted(channel, version, reason string) error {
+	return n.send("rollout_halted", notificationTemplateData{Channel: channel, Version: version, Reason: reason})
+}
+
+func (n *emailNotifier) OnTrafficAnomaly(channel, reason string) error {
+	return n.send("traffic_anomaly", notificationTemplateData{Channel: channel, Reason: reason})
+}
+
+func (n *emailNotifier) OnResourceAlert(reason string) error {
+	return n.send("resource_alert", notificationTemplateData{Reason: reason})
+}
+
+func (n *emailNotifier) OnSizeRegression(channel, version, reason string) error {
+	return n.send("size_regression", notificationTemplateData{Channel: channel, Version: version, Reason: reason})
+}
+
+func (n *emailNotifier) OnDependencyChanged(channel, version, reason string) error {
+	return n.send("dependency_changed", notificationTemplateData{Channel: channel, Version: version, Reason: reason})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type matrixNotifier struct {
+	homeserver  string
+	accessToken string
+	rooms       []string
+	txnCounter  atomic.Uint64
+}
+
+func newMatrixNotifier(cfg NotifierConfig) (*matrixNotifier, error) {
+	if cfg.Url == "" || cfg.AccessToken == "" || len(cfg.Rooms) == 0 {
+		return nil, fmt.Errorf("Matrix notifier requires url, accessToken and rooms")
+	}
+	return &matrixNotifier{homeserver: strings.TrimSuffix(cfg.Url, "/"), accessToken: cfg.AccessToken, rooms: cfg.Rooms}, nil
+}
+
+func (n *matrixNotifier) send(plain, formatted string) error {
+	body, err := json.Marshal(map[string]string{
+		"msgtype":        "m.notice",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, room := range n.rooms {
+		txnId := fmt.Sprintf("selene-%d-%d", time.Now().UnixNano(), n.txnCounter.Add(1))
+		endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", n.homeserver, url.PathEscape(room), txnId)
+		req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+n.accessToken)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", room, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			errs = append(errs, fmt.Sprintf("%s: %s", room, resp.Status))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Matrix delivery failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (n *matrixNotifier) OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error {
+	formatted := fmt.Sprintf("<strong>New Selene release on %s: %s</strong>", html.EscapeString(channel), html.EscapeString(manifest.Version))
+	if previousVersion != "" {
+		formatted += fmt.Sprintf(" (previously %s)", html.EscapeString(previousVersion))
+	}
+	formatted += fmt.Sprintf("<br><a href=\"%s\">%s</a>", html.EscapeString(manifest.Url), html.EscapeString(manifest.FileName))
+	return n.send(newReleaseMessage(channel, manifest, previousVersion), formatted)
+}
+
+func (n *matrixNotifier) OnResolutionFailure(channel string, err error) error {
+	plain := fmt.Sprintf("Failed to resolve latest version for %s: %v", channel, err)
+	return n.send(plain, html.EscapeString(plain))
+}
+
+func (n *matrixNotifier) OnRolloutHalted(channel, version, reason string) error {
+	plain := fmt.Sprintf("Rollout of %s on %s halted: %s", version, channel, reason)
+	return n.send(plain, html.EscapeString(plain))
+}
+
+func (n *matrixNotifier) OnTrafficAnomaly(channel, reason string) error {
+	plain := fmt.Sprintf("Unusual traffic on %s: %s", channel, reason)
+	return n.send(plain, html.EscapeString(plain))
+}
+
+func (n *matrixNotifier) OnResourceAlert(reason string) error {
+	plain := "Update server resource alert: " + reason
+	return n.send(plain, html.EscapeString(plain))
+}
+
+func (n *matrixNotifier) OnSizeRegression(channel, version, reason string) error {
+	plain := fmt.Sprintf("Download size regression in %s %s: %s", channel, version, reason)
+	return n.send(plain, html.EscapeString(plain))
+}
+
+func (n *matrixNotifier) OnDependencyChanged(channel, version, reason string) error {
+	plain := fmt.Sprintf("Pending release %s on %s changes dependencies: %s", version, channel, reason)
+	return n.send(plain, html.EscapeString(plain))
+}
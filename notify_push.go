@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+type ntfyNotifier struct {
+	url         string
+	accessToken string
+}
+
+func (n *ntfyNotifier) send(title, message, tags, click string) error {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewBufferString(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", title)
+	if tags != "" {
+		req.Header.Set("Tags", tags)
+	}
+	if click != "" {
+		req.Header.Set("Click", click)
+	}
+	if n.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+n.accessToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ntfy returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *ntfyNotifier) OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error {
+	return n.send(fmt.Sprintf("Selene %s %s", channel, manifest.Version), newReleaseMessage(channel, manifest, previousVersion), "package", manifest.Url)
+}
+
+func (n *ntfyNotifier) OnResolutionFailure(channel string, err error) error {
+	return n.send(fmt.Sprintf("Selene %s resolution failure", channel), err.Error(), "warning", "")
+}
+
+func (n *ntfyNotifier) OnRolloutHalted(channel, version, reason string) error {
+	return n.send(fmt.Sprintf("Selene %s rollout of %s halted", channel, version), reason, "warning", "")
+}
+
+func (n *ntfyNotifier) OnTrafficAnomaly(channel, reason string) error {
+	return n.send(fmt.Sprintf("Selene %s unusual traffic", channel), reason, "chart_with_upwards_trend", "")
+}
+
+func (n *ntfyNotifier) OnResourceAlert(reason string) error {
+	return n.send("Selene update server resource alert", reason, "warning", "")
+}
+
+func (n *ntfyNotifier) OnSizeRegression(channel, version, reason string) error {
+	return n.send(fmt.Sprintf("Selene %s %s download size regression", channel, version), reason, "package", "")
+}
+
+func (n *ntfyNotifier) OnDependencyChanged(channel, version, reason string) error {
+	return n.send(fmt.Sprintf("Selene %s %s changes your dependencies", channel, version), reason, "jigsaw", "")
+}
+
+type templatedWebhookNotifier struct {
+	url         string
+	contentType string
+	body        *template.Template
+}
+
+func newTemplatedWebhookNotifier(cfg NotifierConfig) (*templatedWebhookNotifier, error) {
+	body, err := template.New("webhook").Parse(cfg.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid webhook body template: %w", err)
+	}
+	contentType := cfg.ContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	return &templatedWebhookNotifier{url: cfg.Url, contentType: contentType, body: body}, nil
+}
+
+type templatedWebhookData struct {
+	notificationTemplateData
+	Event string
+}
+
+func (n *templatedWebhookNotifier) send(data templatedWebhookData) error {
+	var body bytes.Buffer
+	if err := n.body.Execute(&body, data); err != nil {
+		return err
+	}
+	resp, err := http.Post(n.url, n.contentType, &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (n *templatedWebhookNotifier) OnNewRelease(channel string, manifest UpdaterResponse, previousVersion string) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Channel: channel, Version: manifest.Version, PreviousVersion: previousVersion, Manifest: manifest}, "new_release"})
+}
+
+func (n *templatedWebhookNotifier) OnResolutionFailure(channel string, err error) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Channel: channel, Error: err.Error()}, "resolution_failure"})
+}
+
+func (n *templatedWebhookNotifier) OnRolloutHalted(channel, version, reason string) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Channel: channel, Version: version, Reason: reason}, "rollout_halted"})
+}
+
+func (n *templatedWebhookNotifier) OnTrafficAnomaly(channel, reason string) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Channel: channel, Reason: reason}, "traffic_anomaly"})
+}
+
+func (n *templatedWebhookNotifier) OnResourceAlert(reason string) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Reason: reason}, "resource_alert"})
+}
+
+func (n *templatedWebhookNotifier) OnSizeRegression(channel, version, reason string) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Channel: channel, Version: version, Reason: reason}, "size_regression"})
+}
+
+func (n *templatedWebhookNotifier) OnDependencyChanged(channel, version, reason string) error {
+	return n.send(templatedWebhookData{notificationTemplateData{Channel: channel, Version: version, Reason: reason}, "dependency_changed"})
+}
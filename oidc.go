@@ -0,0 +1,378 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type OidcConfig struct {
+	Issuer       string `json:"issuer" required:"true"`
+	ClientId     string `json:"clientId" required:"true"`
+	ClientSecret string `json:"clientSecret"`
+	RedirectUrl  string `json:"redirectUrl" required:"true"`
+	// RoleClaim names the ID token claim holding the user's groups or roles.
+	RoleClaim string `json:"roleClaim,omitempty"`
+	// Roles maps claim values to admin roles (viewer, operator or admin).
+	Roles         map[string]string `json:"roles"`
+	SessionSecret string            `json:"sessionSecret,omitempty"`
+}
+
+const (
+	roleModder   = "modder"
+	roleViewer   = "viewer"
+	roleOperator = "operator"
+	roleAdmin    = "admin"
+
+	adminSessionCookie = "selene_admin_session"
+	oidcStateCookie    = "selene_oidc_state"
+	adminSessionTTL    = 8 * time.Hour
+)
+
+var adminRoles = []string{roleModder, roleViewer, roleOperator, roleAdmin}
+
+func roleRank(role string) int {
+	return slices.Index(adminRoles, role)
+}
+
+type oidcProvider struct {
+	cfg           OidcConfig
+	sessionKey    []byte
+	authorizeUrl  string
+	tokenUrl      string
+	jwksUrl       string
+	mu            sync.Mutex
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+var oidc *oidcProvider
+
+func newOidcProvider(cfg OidcConfig) (*oidcProvider, error) {
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "groups"
+	}
+	for value, role := range cfg.Roles {
+		if roleRank(role) < 0 {
+			return nil, fmt.Errorf("Unknown admin role %q for %q", role, value)
+		}
+	}
+	p := &oidcProvider{cfg: cfg, keys: make(map[string]*rsa.PublicKey)}
+	if cfg.SessionSecret != "" {
+		p.sessionKey = []byte(cfg.SessionSecret)
+	} else {
+		p.sessionKey = make([]byte, 32)
+		rand.Read(p.sessionKey)
+	}
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JwksUri               string `json:"jwks_uri"`
+	}
+	if err := getJson(strings.TrimSuffix(cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if discovery.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("OIDC issuer mismatch: discovered %q", discovery.Issuer)
+	}
+	p.authorizeUrl, p.tokenUrl, p.jwksUrl = discovery.AuthorizationEndpoint, discovery.TokenEndpoint, discovery.JwksUri
+	return p, nil
+}
+
+func getJson(url string, v any) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign and verify implement the value.mac format used by both the session
+// and the login state cookies.
+func (p *oidcProvider) sign(v any) string {
+	data, _ := json.Marshal(v)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	mac := hmac.New(sha256.New, p.sessionKey)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (p *oidcProvider) verify(value string, v any) bool {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha256.New, p.sessionKey)
+	mac.Write([]byte(payload))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(sig), []byte(expected)) != 1 {
+		return false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	return err == nil && json.Unmarshal(data, v) == nil
+}
+
+type adminSession struct {
+	Subject string    `json:"sub"`
+	Role    string    `json:"role"`
+	Expires time.Time `json:"exp"`
+}
+
+type oidcLoginState struct {
+	State   string    `json:"state"`
+	Nonce   string    `json:"nonce"`
+	Expires time.Time `json:"exp"`
+}
+
+func setCookie(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/admin/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (p *oidcProvider) Session(r *http.Request) (adminSession, bool) {
+	cookie, err := r.Cookie(adminSessionCookie)
+	if err != nil {
+		return adminSession{}, false
+	}
+	var session adminSession
+	if !p.verify(cookie.Value, &session) || clock.Now().After(session.Expires) {
+		return adminSession{}, false
+	}
+	return session, true
+}
+
+func (p *oidcProvider) Login(w http.ResponseWriter, r *http.Request) {
+	state := oidcLoginState{State: randomToken(), Nonce: randomToken(), Expires: clock.Now().Add(10 * time.Minute)}
+	setCookie(w, oidcStateCookie, p.sign(state), 10*time.Minute)
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.cfg.ClientId},
+		"redirect_uri":  {p.cfg.RedirectUrl},
+		"scope":         {"openid profile email"},
+		"state":         {state.State},
+		"nonce":         {state.Nonce},
+	}
+	http.Redirect(w, r, p.authorizeUrl+"?"+query.Encode(), http.StatusFound)
+}
+
+func (p *oidcProvider) Callback(w http.ResponseWriter, r *http.Request) {
+	var state oidcLoginState
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || !p.verify(cookie.Value, &state) || clock.Now().After(state.Expires) || r.URL.Query().Get("state") != state.State {
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	setCookie(w, oidcStateCookie, "", -time.Second)
+	claims, err := p.exchange(r.URL.Query().Get("code"), state.Nonce)
+	if err != nil {
+		http.Error(w, "Login failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	role := p.mapRole(claims)
+	if role == "" {
+		http.Error(w, "No admin role granted", http.StatusForbidden)
+		return
+	}
+	subject, _ := claims["sub"].(string)
+	session := adminSession{Subject: subject, Role: role, Expires: clock.Now().Add(adminSessionTTL)}
+	setCookie(w, adminSessionCookie, p.sign(session), adminSessionTTL)
+	http.Redirect(w, r, "/admin/", http.StatusFound)
+}
+
+func (p *oidcProvider) Logout(w http.ResponseWriter, r *http.Request) {
+	setCookie(w, adminSessionCookie, "", -time.Second)
+	http.Redirect(w, r, "/admin/", http.StatusFound)
+}
+
+// mapRole grants the highest role any of the user's claim values maps to.
+func (p *oidcProvider) mapRole(claims map[string]any) string {
+	var values []string
+	switch v := claims[p.cfg.RoleClaim].(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	best := ""
+	for _, value := range values {
+		if role, ok := p.cfg.Roles[value]; ok && roleRank(role) > roleRank(best) {
+			best = role
+		}
+	}
+	return best
+}
+
+func (p *oidcProvider) exchange(code, nonce string) (map[string]any, error) {
+	if code == "" {
+		return nil, errors.New("Missing authorization code")
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.cfg.RedirectUrl},
+		"client_id":    {p.cfg.ClientId},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	resp, err := http.PostForm(p.tokenUrl, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Token endpoint returned %s", resp.Status)
+	}
+	var tokens struct {
+		IdToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, err
+	}
+	claims, err := p.verifyIdToken(tokens.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("Nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) verifyIdToken(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("Malformed ID token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("Unsupported ID token algorithm %q", header.Alg)
+	}
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, errors.New("Invalid ID token signature")
+	}
+	var claims map[string]any
+	if err := decodeJwtPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if claims["iss"] != p.cfg.Issuer {
+		return nil, errors.New("ID token issuer mismatch")
+	}
+	switch aud := claims["aud"].(type) {
+	case string:
+		if aud != p.cfg.ClientId {
+			return nil, errors.New("ID token audience mismatch")
+		}
+	case []any:
+		if !slices.Contains(aud, any(p.cfg.ClientId)) {
+			return nil, errors.New("ID token audience mismatch")
+		}
+	default:
+		return nil, errors.New("ID token audience missing")
+	}
+	exp, _ := claims["exp"].(float64)
+	if clock.Now().After(time.Unix(int64(exp), 0).Add(clock.tolerance)) {
+		return nil, errors.New("ID token expired")
+	}
+	return claims, nil
+}
+
+func decodeJwtPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// key returns the signing key with the given id, refetching the JWKS when
+// an unknown key id shows up (at most once a minute) to follow rotation.
+func (p *oidcProvider) key(kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < time.Minute {
+		return nil, fmt.Errorf("Unknown signing key %q", kid)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJson(p.jwksUrl, &jwks); err != nil {
+		return nil, err
+	}
+	p.keysFetchedAt = time.Now()
+	clear(p.keys)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		p.keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("Unknown signing key %q", kid)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// PlatformRule marks libraries whose file name matches Pattern (a path.Match
+// glob) as only needed on the given OS and, if set, architecture. Libraries
+// matching no rule are needed everywhere.
+type PlatformRule struct {
+	Pattern string `json:"pattern" required:"true"`
+	Os      string `json:"os" required:"true"`
+	Arch    string `json:"arch,omitempty"`
+}
+
+// defaultPlatformRules cover LWJGL-style natives classifiers.
+var defaultPlatformRules = []PlatformRule{
+	{Pattern: "*-natives-windows.jar", Os: "windows", Arch: "x86_64"},
+	{Pattern: "*-natives-windows-x86.jar", Os: "windows", Arch: "x86"},
+	{Pattern: "*-natives-windows-arm64.jar", Os: "windows", Arch: "arm64"},
+	{Pattern: "*-natives-linux.jar", Os: "linux", Arch: "x86_64"},
+	{Pattern: "*-natives-linux-arm64.jar", Os: "linux", Arch: "arm64"},
+	{Pattern: "*-natives-linux-arm32.jar", Os: "linux", Arch: "arm32"},
+	{Pattern: "*-natives-macos.jar", Os: "macos", Arch: "x86_64"},
+	{Pattern: "*-natives-macos-arm64.jar", Os: "macos", Arch: "arm64"},
+}
+
+var osAliases = map[string]string{
+	"windows": "windows", "win": "windows",
+	"linux": "linux",
+	"macos": "macos", "osx": "macos", "mac": "macos", "darwin": "macos",
+}
+
+var archAliases = map[string]string{
+	"x86_64": "x86_64", "amd64": "x86_64", "x64": "x86_64",
+	"x86": "x86", "i386": "x86", "i686": "x86",
+	"arm64": "arm64", "aarch64": "arm64",
+	"arm32": "arm32", "arm": "arm32",
+}
+
+type platform struct {
+	Os   string
+	Arch string
+}
+
+// parsePlatform reads ?os= and ?arch=. An empty platform means no filtering.
+func parsePlatform(r *http.Request) (platform, error) {
+	query := r.URL.Query()
+	var p platform
+	if os := strings.ToLower(query.Get("os")); os != "" {
+		if p.Os = osAliases[os]; p.Os == "" {
+			return p, fmt.Errorf("Unknown os %q", os)
+		}
+	}
+	if arch := strings.ToLower(query.Get("arch")); arch != "" {
+		if p.Os == "" {
+			return p, fmt.Errorf("Parameter arch requires os")
+		}
+		if p.Arch = archAliases[arch]; p.Arch == "" {
+			return p, fmt.Errorf("Unknown arch %q", arch)
+		}
+	}
+	return p, nil
+}
+
+func validatePlatformRules(rules []PlatformRule) error {
+	for _, rule := range rules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("Invalid platform pattern %q", rule.Pattern)
+		}
+		if osAliases[rule.Os] != rule.Os {
+			return fmt.Errorf("Unknown os %q in platform rule %q", rule.Os, rule.Pattern)
+		}
+		if rule.Arch != "" && archAliases[rule.Arch] != rule.Arch {
+			return fmt.Errorf("Unknown arch %q in platform rule %q", rule.Arch, rule.Pattern)
+		}
+	}
+	return nil
+}
+
+func (p platform) needs(fileName string, rules []PlatformRule) bool {
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.Pattern, fileName); ok {
+			return rule.Os == p.Os && (p.Arch == "" || rule.Arch == "" || rule.Arch == p.Arch)
+		}
+	}
+	return true
+}
+
+// filterLibraries drops libraries (and their checksums) that the platform
+// does not need.
+func filterLibraries(resp UpdaterResponse, p platform, rules []PlatformRule) UpdaterResponse {
+	if p.Os == "" {
+		return resp
+	}
+	libraries := make(map[string]string, len(resp.Libraries))
+	for fileName, url := range resp.Libraries {
+		if p.needs(fileName, rules) {
+			libraries[fileName] = url
+		}
+	}
+	resp.Libraries = libraries
+	if resp.LibrarySha256 != nil {
+		checksums := make(map[string]string, len(libraries))
+		for fileName := range libraries {
+			if sum, ok := resp.LibrarySha256[fileName]; ok {
+				checksums[fileName] = sum
+			}
+		}
+		resp.LibrarySha256 = checksums
+	}
+	return resp
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+// Minimal protobuf encoding for the messages in proto/, kept by hand so the
+// server does not need generated code for a single flat message.
+
+var errMalformedProto = errors.New("Malformed protobuf message")
+
+const (
+	protoWireVarint = 0
+	protoWireBytes  = 2
+)
+
+func appendProtoTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoBytes(b []byte, field int, value []byte) []byte {
+	b = appendProtoTag(b, field, protoWireBytes)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendProtoString(b []byte, field int, value string) []byte {
+	if value == "" {
+		return b
+	}
+	return appendProtoBytes(b, field, []byte(value))
+}
+
+func appendProtoBool(b []byte, field int, value bool) []byte {
+	if !value {
+		return b
+	}
+	return append(appendProtoTag(b, field, protoWireVarint), 1)
+}
+
+func appendProtoUint(b []byte, field int, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendProtoTag(b, field, protoWireVarint), value)
+}
+
+func appendProtoStringMap(b []byte, field int, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendProtoString(entry, 1, k)
+		entry = appendProtoString(entry, 2, m[k])
+		b = appendProtoBytes(b, field, entry)
+	}
+	return b
+}
+
+func encodeManifestProto(resp UpdaterResponse) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, resp.Version)
+	b = appendProtoString(b, 2, resp.PubDate)
+	b = appendProtoString(b, 3, resp.Url)
+	b = appendProtoString(b, 4, resp.FileName)
+	b = appendProtoStringMap(b, 5, resp.Libraries)
+	b = appendProtoString(b, 6, resp.Sha256)
+	b = appendProtoStringMap(b, 7, resp.LibrarySha256)
+	b = appendProtoBool(b, 8, resp.Stale)
+	if resp.Theme != nil {
+		b = appendProtoBytes(b, 9, encodeThemeProto(resp.Theme))
+	}
+	b = appendProtoUint(b, 10, uint64(resp.NextCheckAfterSeconds))
+	return b
+}
+
+// decodeProtoStrings returns the length-delimited fields of a flat message by
+// field number, which covers every request message in proto/.
+func decodeProtoStrings(b []byte) (map[int]string, error) {
+	fields := map[int]string{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errMalformedProto
+		}
+		b = b[n:]
+		switch tag & 7 {
+		case protoWireVarint:
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return nil, errMalformedProto
+			}
+		case 1:
+			n = 8
+		case protoWireBytes:
+			length, m := binary.Uvarint(b)
+			if m <= 0 || length > uint64(len(b)-m) {
+				return nil, errMalformedProto
+			}
+			fields[int(tag>>3)] = string(b[m : m+int(length)])
+			n = m + int(length)
+		case 5:
+			n = 4
+		default:
+			return nil, errMalformedProto
+		}
+		if n > len(b) {
+			return nil, errMalformedProto
+		}
+		b = b[n:]
+	}
+	return fields, nil
+}
+
+func encodeVersionListProto(versions []string) []byte {
+	var b []byte
+	for _, v := range versions {
+		b = appendProtoBytes(b, 1, []byte(v))
+	}
+	return b
+}
+
+func encodeReleaseEventProto(ch channel, e Event) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, string(e.Type))
+	b = appendProtoString(b, 2, e.Channel)
+	b = appendProtoString(b, 3, e.Version)
+	b = appendProtoString(b, 4, e.PreviousVersion)
+	if e.Manifest != nil {
+		b = appendProtoBytes(b, 5, encodeManifestProto(grpcManifest(ch, *e.Manifest)))
+	}
+	b = appendProtoUint(b, 6, uint64(e.Time.Unix()))
+	return b
+}
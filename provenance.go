@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// intotoContentType is served for in-toto attestation bundles, one DSSE
+// envelope per line.
+const intotoContentType = "application/jsonl; charset=utf-8"
+
+// provenanceDocument is a provenance asset with the media type it is served as.
+type provenanceDocument struct {
+	Data        []byte
+	ContentType string
+}
+
+var provenanceCache = newNamedLruCache[string, provenanceDocument]("provenance", 256)
+
+func findProvenanceAsset(item nexusItem) (nexusAsset, bool) {
+	if asset, ok := item.findAsset("provenance", "intoto.jsonl"); ok {
+		return asset, true
+	}
+	return item.findAsset("provenance", "json")
+}
+
+func fetchProvenance(repo, group, artifact, version string) (provenanceDocument, error) {
+	cacheKey := repo + ":" + group + ":" + artifact + ":" + version
+	if doc, ok := provenanceCache.Get(cacheKey); ok {
+		return doc, nil
+	}
+	item, err := findNexusVersion(repo, group, artifact, version)
+	if err != nil {
+		return provenanceDocument{}, err
+	}
+	asset, ok := findProvenanceAsset(item)
+	if !ok {
+		return provenanceDocument{}, &nexusStatusError{Status: "404 Provenance Not Found", StatusCode: http.StatusNotFound}
+	}
+	resp, err := upstreamGet(transformToPublicUrl(asset.DownloadUrl))
+	if err != nil {
+		return provenanceDocument{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return provenanceDocument{}, fmt.Errorf("Failed to fetch provenance asset: %w", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode})
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return provenanceDocument{}, err
+	}
+	doc := provenanceDocument{Data: data, ContentType: jsonContentType}
+	if asset.Maven2.Extension == "intoto.jsonl" {
+		doc.ContentType = intotoContentType
+	}
+	provenanceCache.Set(cacheKey, doc)
+	return doc, nil
+}
+
+func provenanceHandler(w http.ResponseWriter, r *http.Request, ch channel, fileName string) {
+	version, ok := strings.CutSuffix(fileName, ".json")
+	if !ok || version == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	doc, err := fetchProvenance(ch.Repository, ch.Group, ch.Artifact, version)
+	if err != nil {
+		writeFailure(w, "Failed to fetch provenance", err)
+		return
+	}
+	writeBody(w, doc.ContentType, doc.Data)
+}
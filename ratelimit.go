@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+type clientRateLimiter struct {
+	perMinute int
+	clients   *lruCache[string, *requestBudget]
+}
+
+func newClientRateLimiter(perMinute int) *clientRateLimiter {
+	return &clientRateLimiter{perMinute: perMinute, clients: newLruCache[string, *requestBudget](10000)}
+}
+
+func clientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (l *clientRateLimiter) budget(r *http.Request) *requestBudget {
+	return l.clients.GetOrCreate(clientIp(r), func() *requestBudget {
+		return newRequestBudget(l.perMinute)
+	})
+}
+
+// Wrap limits next per client IP. Every response carries the client's
+// budget in X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// (Unix seconds when the budget is full again), so tools can slow down
+// before they are turned away.
+func (l *clientRateLimiter) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.perMinute <= 0 {
+			next(w, r)
+			return
+		}
+		budget := l.budget(r)
+		allowed := budget.Take()
+		remaining, reset := budget.Remaining()
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(l.perMinute))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(60/max(l.perMinute, 1), 1)))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
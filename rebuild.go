@@ -0,0 +1,179 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type rebuildAttestation struct {
+	Builder     string    `json:"builder"`
+	Sha256      string    `json:"sha256"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+type rebuildStore struct {
+	mu      sync.Mutex
+	path    string
+	reports map[string][]rebuildAttestation
+}
+
+func newRebuildStore(path string) *rebuildStore {
+	return &rebuildStore{path: path, reports: make(map[string][]rebuildAttestation)}
+}
+
+func (s *rebuildStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.reports)
+}
+
+func (s *rebuildStore) Submit(version string, attestation rebuildAttestation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attestations := s.reports[version]
+	for i, existing := range attestations {
+		if existing.Builder == attestation.Builder {
+			attestations = append(attestations[:i], attestations[i+1:]...)
+			break
+		}
+	}
+	s.reports[version] = append(attestations, attestation)
+	if s.path == "" {
+		return nil
+	}
+	return writeJsonFile(s.path, s.reports)
+}
+
+func (s *rebuildStore) Get(version string) []rebuildAttestation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]rebuildAttestation(nil), s.reports[version]...)
+}
+
+var rebuilds = newRebuildStore("")
+
+func authenticateRebuilder(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+	for name, expected := range config().Rebuilders {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func rebuildHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mirror != nil {
+		http.Error(w, "Read-only mirror", http.StatusForbidden)
+		return
+	}
+	builder, ok := authenticateRebuilder(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	product, version, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/rebuilds/"), "/")
+	if !ok || version == "" || strings.Contains(version, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if _, err := artifacts.Lookup(product); err != nil {
+		writeFailure(w, "", err)
+		return
+	}
+	var body struct {
+		Sha256 string `json:"sha256"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body.Sha256 = strings.ToLower(body.Sha256)
+	if !sha256Hex.MatchString(body.Sha256) {
+		http.Error(w, "Invalid sha256", http.StatusBadRequest)
+		return
+	}
+	if err := rebuilds.Submit(product+"/"+version, rebuildAttestation{Builder: builder, Sha256: body.Sha256, SubmittedAt: clock.Now().UTC()}); err != nil {
+		writePersistFailure(w, "rebuild attestations", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type readinessReport struct {
+	Version       string               `json:"version"`
+	Ready         bool                 `json:"ready"`
+	HasJar        bool                 `json:"hasJar"`
+	HasLibraries  bool                 `json:"hasLibraries"`
+	HasProvenance bool                 `json:"hasProvenance"`
+	JarSha256     string               `json:"jarSha256,omitempty"`
+	Reproducible  string               `json:"reproducible"`
+	Rebuilds      []rebuildAttestation `json:"rebuilds"`
+	Problems      []string             `json:"problems,omitempty"`
+}
+
+func buildReadinessReport(product string, selectors map[string]AssetSelector, item nexusItem) readinessReport {
+	report := readinessReport{Version: item.Version, Reproducible: "unverified", Rebuilds: rebuilds.Get(product + "/" + item.Version)}
+	jar, hasJar := item.findRole(selectors, rolePrimary)
+	_, report.HasLibraries = item.findRole(selectors, roleLibraries)
+	_, report.HasProvenance = findProvenanceAsset(item)
+	report.HasJar = hasJar
+	report.JarSha256 = strings.ToLower(jar.Checksum.Sha256)
+	if !report.HasJar {
+		report.Problems = append(report.Problems, "missing dist jar")
+	}
+	if !report.HasLibraries {
+		report.Problems = append(report.Problems, "missing libraries.json")
+	}
+	if config().RequireProvenance && !report.HasProvenance {
+		report.Problems = append(report.Problems, "missing provenance attestation")
+	}
+	if len(report.Rebuilds) > 0 && report.JarSha256 != "" {
+		report.Reproducible = "verified"
+		for _, rebuild := range report.Rebuilds {
+			if rebuild.Sha256 != report.JarSha256 {
+				report.Reproducible = "mismatch"
+				report.Problems = append(report.Problems, "rebuild by "+rebuild.Builder+" produced a different jar hash")
+			}
+		}
+	}
+	report.Ready = len(report.Problems) == 0
+	return report
+}
+
+func readinessHandler(w http.ResponseWriter, r *http.Request, ch channel, fileName string) {
+	version, ok := strings.CutSuffix(fileName, ".json")
+	if !ok || version == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	item, err := findNexusVersion(ch.Repository, ch.Group, ch.Artifact, version)
+	if err != nil {
+		writeFailure(w, "Failed to fetch version", err)
+		return
+	}
+	report := buildReadinessReport(ch.Product, ch.Assets, item)
+	if report.Reproducible == "mismatch" {
+		log.Printf("Warning: reproducible build mismatch for %s", version)
+	}
+	writeJsonResponse(w, "readiness", report)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// With refreshIntervalSeconds set, the scheduler re-resolves every channel
+// on that interval, so update checks are answered from the cache and Nexus
+// sees the same load however many launchers are checking. Cached manifests
+// outlive a few missed refreshes before requests fall back to resolving on
+// demand.
+const refreshTTLFactor = 5
+
+func refreshInterval() time.Duration {
+	return time.Duration(config().RefreshIntervalSeconds) * time.Second
+}
+
+// refreshChannels resolves every channel past the cache and replaces the
+// cached manifest; the old one keeps being served until then. It is both the
+// interval refresh and the "revalidate" schedule.
+func refreshChannels() error {
+	for _, ch := range allChannels() {
+		_, err, _ := resolveFlights.Do(ch.Key(), func() (UpdaterResponse, error) {
+			return resolveUncached(ch, nil)
+		})
+		if err != nil {
+			log.Printf("Warning: background refresh failed for %s: %v", ch.Key(), err)
+		}
+	}
+	return nil
+}
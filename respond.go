@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Every JSON, XML, HTML and text response declares UTF-8 explicitly.
+const (
+	jsonContentType  = "application/json; charset=utf-8"
+	xmlContentType   = "application/xml; charset=utf-8"
+	textContentType  = "text/plain; charset=utf-8"
+	htmlContentType  = "text/html; charset=utf-8"
+	protoContentType = "application/x-protobuf; messageType=selene.updater.v1.Manifest"
+)
+
+// encodingFailureJson is sent instead of a response that cannot be encoded.
+var encodingFailureJson = []byte(`{"error":"Failed to encode response","reason":"internal_error"}` + "\n")
+
+func encodeJson(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	return append(data, '\n'), err
+}
+
+func writeJsonBytes(w http.ResponseWriter, status int, data []byte) {
+	contentType := w.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = jsonContentType
+	}
+	writeBodyStatus(w, status, contentType, data)
+}
+
+// writeBody writes a complete response body with its exact length, which
+// some CDNs and HTTP clients need to cache or accept a response.
+func writeBody(w http.ResponseWriter, contentType string, data []byte) {
+	writeBodyStatus(w, http.StatusOK, contentType, data)
+}
+
+// writeBodyStatus is writeBody with another status. Handlers pass the status
+// here instead of sending it first, as headers set after it are dropped.
+func writeBodyStatus(w http.ResponseWriter, status int, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeEncodingFailure answers 500 with a clean error document.
+func writeEncodingFailure(w http.ResponseWriter, v any, err error) {
+	log.Printf("Error: failed to encode %T response: %v", v, err)
+	w.Header().Set("Content-Type", jsonContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(encodingFailureJson)))
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write(encodingFailureJson)
+}
+
+// writeJson encodes v completely before writing anything, so a value that
+// cannot be encoded results in a clean error document rather than a
+// truncated or empty body.
+func writeJson(w http.ResponseWriter, v any) {
+	writeJsonStatus(w, http.StatusOK, v)
+}
+
+// writeJsonStatus is writeJson with another status, which an encoding
+// failure replaces with 500.
+func writeJsonStatus(w http.ResponseWriter, status int, v any) {
+	data, err := encodeJson(v)
+	if err != nil {
+		writeEncodingFailure(w, v, err)
+		return
+	}
+	writeJsonBytes(w, status, data)
+}
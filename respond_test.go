@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// checkEncodingFailure asserts the clean 500 a value that cannot be encoded
+// is answered with, whatever status the handler asked for.
+func checkEncodingFailure(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", rec.Code)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), encodingFailureJson) {
+		t.Errorf("body %q, want %q", rec.Body, encodingFailureJson)
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(len(encodingFailureJson)) {
+		t.Errorf("Content-Length %q, want %d", got, len(encodingFailureJson))
+	}
+	if got := rec.Header().Get("Content-Type"); got != jsonContentType {
+		t.Errorf("Content-Type %q, want %q", got, jsonContentType)
+	}
+}
+
+func TestWriteJsonEncodingFailure(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusServiceUnavailable} {
+		t.Run(strconv.Itoa(status), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeJsonStatus(rec, status, map[string]float64{"ratio": math.NaN()})
+			checkEncodingFailure(t, rec)
+		})
+	}
+}
+
+func TestWriteJsonResponseEncodingFailure(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJsonResponseStatus(rec, http.StatusGone, "retired", map[string]any{"error": make(chan int)})
+	checkEncodingFailure(t, rec)
+}
+
+func TestWriteJsonStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeJsonStatus(rec, http.StatusServiceUnavailable, map[string]string{"status": "failing"})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Content-Length %q, body is %d bytes", got, rec.Body.Len())
+	}
+}
+
+// TestJsonHandlers runs each JSON endpoint against a fake Nexus and checks
+// the status, that the body is one complete JSON document and that
+// Content-Length matches it, including where the status is not 200.
+func TestJsonHandlers(t *testing.T) {
+	startFakeNexus(t)
+	useConfig(t, func(cfg *Config) {
+		cfg.RetiredChannels = map[string]string{"selene-client/legacy": "selene-client/stable"}
+	})
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+		status  int
+	}{
+		{"latest", channelHandler, "/selene-client/stable/latest.json", 200},
+		{"latest v1", channelHandler, "/selene-client/stable/v1/latest.json", 200},
+		{"version", channelHandler, "/selene-client/stable/1.1.0.json", 200},
+		{"channels", channelHandler, "/selene-client/channels.json", 200},
+		{"retired", channelHandler, "/selene-client/legacy/latest.json", 410},
+		{"artifacts", artifactsHandler, "/artifacts.json", 200},
+		{"deep health", deepHealthHandler, "/healthz/deep", 200},
+		{"version info", buildInfoHandler, "/version", 200},
+		{"keys", keysHandler, "/keys", 200},
+		{"schema", schemaHandler, "/schemas/manifest.json", 200},
+		{"search", searchHandler, "/search?artifact=selene-client&q=1.2", 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest("GET", tt.path, nil))
+			checkJsonResponse(t, rec, tt.status)
+		})
+	}
+}
+
+// TestJsonHandlersNexusDown checks the documents answered with an error
+// status while Nexus is unavailable.
+func TestJsonHandlersNexusDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	useNexus(t, server.URL)
+
+	rec := httptest.NewRecorder()
+	deepHealthHandler(rec, httptest.NewRequest("GET", "/healthz/deep", nil))
+	checkJsonResponse(t, rec, http.StatusServiceUnavailable)
+
+	rec = serveTest(httptest.NewRequest("GET", "/selene-client/stable/latest.json", nil))
+	if rec.Code < 500 {
+		t.Fatalf("status %d, want an upstream failure: %s", rec.Code, rec.Body)
+	}
+	checkJsonResponse(t, rec, rec.Code)
+}
+
+func checkJsonResponse(t *testing.T, rec *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status %d, want %d: %s", rec.Code, status, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Content-Length %q, body is %d bytes", got, rec.Body.Len())
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("body is not JSON: %s", rec.Body)
+	}
+}
+
+// TestResponseHeaders checks that every buffered response declares its
+// charset and exact length, whatever its status.
+func TestResponseHeaders(t *testing.T) {
+	startFakeNexus(t)
+	tests := []struct {
+		handler     http.HandlerFunc
+		path        string
+		status      int
+		contentType string
+	}{
+		{channelHandler, "/selene-client/stable/latest.json", 200, jsonContentType},
+		{channelHandler, "/selene-client/stable/v1/latest.json", 200, jsonContentType},
+		{channelHandler, "/selene-client/stable/1.1.0.json", 200, jsonContentType},
+		{channelHandler, "/selene-client/channels.json", 200, jsonContentType},
+		{channelHandler, "/selene-client/stable/latest.pb", 200, protoContentType},
+		{channelHandler, "/selene-client/stable/feed.xml", 200, atomContentType},
+		{channelHandler, "/selene-client/stable/appcast.xml", 200, appcastContentType},
+		{channelHandler, "/selene-client/stable/update4j.xml", 200, xmlContentType},
+		{channelHandler, "/selene-client/stable/changelog.md", 200, "text/markdown; charset=utf-8"},
+		{channelHandler, "/selene-client/stable/changelog.html", 200, htmlContentType},
+		{artifactsHandler, "/artifacts.json", 200, jsonContentType},
+		{healthHandler, "/healthz", 200, textContentType},
+		{readyHandler, "/readyz", 503, textContentType},
+		{deepHealthHandler, "/healthz/deep", 200, jsonContentType},
+		{buildInfoHandler, "/version", 200, jsonContentType},
+		{keysHandler, "/keys", 200, jsonContentType},
+		{schemaHandler, "/schemas/manifest.json", 200, "application/schema+json; charset=utf-8"},
+		{searchHandler, "/search?artifact=selene-client&q=1.2", 200, jsonContentType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type %q, want %q", got, tt.contentType)
+			}
+			if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+				t.Errorf("Content-Length %q, body is %d bytes", got, rec.Body.Len())
+			}
+		})
+	}
+}
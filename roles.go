@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+type AdminTokenConfig struct {
+	Name  string `json:"name" required:"true"`
+	Token string `json:"token" required:"true"`
+	Role  string `json:"role" required:"true"`
+}
+
+// Admin scopes. Read access covers status views; release operations change
+// what clients are offered; system operations change the server itself.
+// Mod operations only manage mod authors' dependency registrations.
+const (
+	scopeStatusRead    = "status:read"
+	scopeReleasesWrite = "releases:write"
+	scopeSystemWrite   = "system:write"
+	scopeModsWrite     = "mods:write"
+)
+
+var roleScopes = map[string][]string{
+	roleModder:   {scopeModsWrite},
+	roleViewer:   {scopeStatusRead},
+	roleOperator: {scopeStatusRead, scopeReleasesWrite, scopeModsWrite},
+	roleAdmin:    {scopeStatusRead, scopeReleasesWrite, scopeSystemWrite, scopeModsWrite},
+}
+
+func roleHasScope(role, scope string) bool {
+	return slices.Contains(roleScopes[role], scope)
+}
+
+type adminOperation struct {
+	method string
+	scope  string
+}
+
+var adminOperations = map[string]adminOperation{
+	"":           {http.MethodGet, scopeStatusRead},
+	"status":     {http.MethodGet, scopeStatusRead},
+	"jobs":       {http.MethodGet, scopeStatusRead},
+	"whoami":     {http.MethodGet, scopeStatusRead},
+	"deliveries": {http.MethodGet, scopeStatusRead},
+	"mirror":     {http.MethodGet, scopeStatusRead},
+	"simulate":   {http.MethodPost, scopeStatusRead},
+	"flush":      {http.MethodPost, scopeReleasesWrite},
+	"promote":    {http.MethodPost, scopeReleasesWrite},
+	"rollback":   {http.MethodPost, scopeReleasesWrite},
+	"rollout":    {http.MethodPost, scopeReleasesWrite},
+	"prepare":    {http.MethodPost, scopeReleasesWrite},
+	"switch":     {http.MethodPost, scopeReleasesWrite},
+	"unslot":     {http.MethodPost, scopeReleasesWrite},
+	"yank":       {http.MethodPost, scopeReleasesWrite},
+	"unyank":     {http.MethodPost, scopeReleasesWrite},
+	"sign":       {http.MethodPost, scopeReleasesWrite},
+	"redeliver":  {http.MethodPost, scopeReleasesWrite},
+	"bundle":     {http.MethodPost, scopeReleasesWrite},
+	"import":     {http.MethodPost, scopeReleasesWrite},
+	"verify":     {http.MethodPost, scopeReleasesWrite},
+	"mods":       {http.MethodGet, scopeModsWrite},
+	"watch":      {http.MethodPost, scopeModsWrite},
+	"unwatch":    {http.MethodPost, scopeModsWrite},
+	"artifacts":  {http.MethodPost, scopeSystemWrite},
+}
+
+func validateAdminTokens(tokens []AdminTokenConfig) error {
+	for _, t := range tokens {
+		if _, ok := roleScopes[t.Role]; !ok {
+			return fmt.Errorf("Admin token %s has unknown role %q", t.Name, t.Role)
+		}
+	}
+	return nil
+}
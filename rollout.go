@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// clientIdHeader identifies a launcher install for staged rollouts. Launchers
+// that cannot set headers may pass ?clientId= instead.
+const clientIdHeader = "X-Selene-Client-Id"
+
+// rollout serves Version to Percentage percent of clients while the rest
+// stay on Baseline, the version the channel advertised before.
+type rollout struct {
+	Version    string `json:"version"`
+	Baseline   string `json:"baseline"`
+	Percentage int    `json:"percentage"`
+}
+
+type rolloutStore struct {
+	mu       sync.RWMutex
+	path     string
+	rollouts map[string]rollout
+}
+
+func newRolloutStore(path string) *rolloutStore {
+	return &rolloutStore{path: path, rollouts: make(map[string]rollout)}
+}
+
+var rollouts = newRolloutStore("")
+
+func (s *rolloutStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.rollouts)
+}
+
+func (s *rolloutStore) Get(channel string) (rollout, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ro, ok := s.rollouts[channel]
+	return ro, ok
+}
+
+// Set starts or updates a rollout, or ends it when ro is nil. The change
+// applies in memory even if it cannot be persisted.
+func (s *rolloutStore) Set(channel string, ro *rollout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ro == nil {
+		delete(s.rollouts, channel)
+	} else {
+		s.rollouts[channel] = *ro
+	}
+	if s.path == "" {
+		return nil
+	}
+	return writeJsonFile(s.path, s.rollouts)
+}
+
+var errNoRolloutBaseline = errors.New("No previous version to keep the other clients on")
+
+// startRollout rolls version out to percentage percent of the channel's
+// clients. Changing only the percentage keeps the baseline and the clients
+// already bucketed into the rollout; 100 percent ends it.
+func startRollout(ch channel, version string, percentage int) error {
+	key := ch.Key()
+	ro, ok := rollouts.Get(key)
+	if !ok || ro.Version != version {
+		current, served := lastServed.Get(key)
+		if !served {
+			return errNoRolloutBaseline
+		}
+		ro = rollout{Version: version, Baseline: current.Version}
+		if ro.Baseline == version {
+			// The new version is already advertised, so the rest of the
+			// clients go back to the one before it.
+			if ro.Baseline = admin.PreviousRelease(key, version); ro.Baseline == "" {
+				return errNoRolloutBaseline
+			}
+		}
+	}
+	if percentage == 100 {
+		return rollouts.Set(key, nil)
+	}
+	ro.Percentage = percentage
+	return rollouts.Set(key, &ro)
+}
+
+func rolloutClientId(r *http.Request) string {
+	if id := r.Header.Get(clientIdHeader); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("clientId")
+}
+
+// rolloutBucket places a client in one of 100 buckets. Hashing the version
+// in samples different clients for each release, while raising the
+// percentage of one rollout only ever adds clients.
+func rolloutBucket(channel, version, clientId string) int {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", channel, version, clientId)
+	return int(h.Sum32() % 100)
+}
+
+// applyRollout swaps the manifest for the version the client is bucketed
+// into while a rollout runs on the channel. Clients without an ID stay on
+// the baseline, and a pin overrides the rollout.
+func applyRollout(h http.Header, r *http.Request, ch channel, resp UpdaterResponse) UpdaterResponse {
+	ro, ok := rollouts.Get(ch.Key())
+	if !ok {
+		return resp
+	}
+	if _, pinned := admin.Pin(ch.Key()); pinned {
+		return resp
+	}
+	h.Add("Vary", clientIdHeader)
+	version := ro.Baseline
+	if id := rolloutClientId(r); id != "" && rolloutBucket(ch.Key(), ro.Version, id) < ro.Percentage {
+		version = ro.Version
+	}
+	if version == resp.Version || admin.IsBlocked(ch.Group+":"+ch.Artifact, version) {
+		return resp
+	}
+	rolled, err := versionManifests.Do(ch.Key()+"@"+version, versionManifestTTL, func() (UpdaterResponse, error) {
+		return releaseManifest(ch, version)
+	})
+	if err != nil {
+		log.Printf("Warning: failed to resolve rollout version %s of %s: %v", version, ch.Key(), err)
+		return resp
+	}
+	return rolled
+}
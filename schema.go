@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// responseSchemas lists every public response type. Schemas are derived from
+// the Go types themselves so they cannot drift from what is actually encoded.
+var responseSchemas = map[string]reflect.Type{
+	"manifest":         reflect.TypeOf(UpdaterResponse{}),
+	"manifest-v1":      reflect.TypeOf(manifestV1{}),
+	"manifest-compact": reflect.TypeOf(compactResponse{}),
+	"manifest-debug":   reflect.TypeOf(debugManifest{}),
+	"batch":            reflect.TypeOf(batchResponse{}),
+	"channels":         reflect.TypeOf(channelList{}),
+	"artifacts":        reflect.TypeOf([]artifactSummary{}),
+	"search":           reflect.TypeOf([]searchResult{}),
+	"readiness":        reflect.TypeOf(readinessReport{}),
+	"error":            reflect.TypeOf(failureResponse{}),
+	"retired":          reflect.TypeOf(retiredResponse{}),
+	"usage":            reflect.TypeOf(map[string]channelUsageReport{}),
+	"tauri":            reflect.TypeOf(tauriManifest{}),
+	"squirrel-darwin":  reflect.TypeOf(squirrelDarwinUpdate{}),
+	"release-event":    reflect.TypeOf(releaseEvent{}),
+}
+
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if field.Anonymous && name == "" {
+				embedded := jsonSchemaFor(field.Type)
+				for k, v := range embedded["properties"].(map[string]any) {
+					properties[k] = v
+				}
+				required = append(required, embedded["required"].([]string)...)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchemaFor(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		slices.Sort(required)
+		return map[string]any{"type": "object", "properties": properties, "required": required, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": jsonSchemaFor(t.Elem())}
+	case reflect.Slice:
+		return map[string]any{"type": []string{"array", "null"}, "items": jsonSchemaFor(t.Elem())}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchemaFor(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Int32, reflect.Uint, reflect.Uint64, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Float64, reflect.Float32:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+func responseSchema(name string) (map[string]any, bool) {
+	t, ok := responseSchemas[name]
+	if !ok {
+		return nil, false
+	}
+	schema := jsonSchemaFor(t)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = "/schemas/" + name + ".json"
+	return schema, true
+}
+
+func schemaHandler(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/schemas/"), ".json")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	schema, ok := responseSchema(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json; charset=utf-8")
+	writeJson(w, schema)
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+func validateAgainstSchema(schema map[string]any, value any, path string) error {
+	types := schemaTypes(schema)
+	if len(types) == 0 {
+		return nil
+	}
+	actual := "null"
+	switch v := value.(type) {
+	case map[string]any:
+		actual = "object"
+	case []any:
+		actual = "array"
+	case string:
+		actual = "string"
+	case bool:
+		actual = "boolean"
+	case float64:
+		actual = "number"
+		if v == float64(int64(v)) {
+			actual = "integer"
+		}
+	}
+	if !slices.Contains(types, actual) && !(actual == "integer" && slices.Contains(types, "number")) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), actual)
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]string); ok {
+			for _, name := range required {
+				if _, ok := v[name]; !ok {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		for key, child := range v {
+			childSchema, ok := properties[key].(map[string]any)
+			if !ok {
+				additional, ok := schema["additionalProperties"].(map[string]any)
+				if !ok {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+				childSchema = additional
+			}
+			if err := validateAgainstSchema(childSchema, child, path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, child := range v {
+			if err := validateAgainstSchema(items, child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateResponse(name string, v any) error {
+	schema, ok := responseSchema(name)
+	if !ok {
+		return fmt.Errorf("Unknown schema %q", name)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	return validateAgainstSchema(schema, decoded, "$")
+}
+
+// writeJsonResponse encodes a public response and, when response validation
+// is enabled, checks it against its published schema first. Manifests are
+// signed when manifest signing is enabled.
+func writeJsonResponse(w http.ResponseWriter, schemaName string, v any) {
+	writeJsonResponseStatus(w, http.StatusOK, schemaName, v)
+}
+
+// writeJsonResponseStatus is writeJsonResponse with another status, which an
+// encoding failure replaces with 500.
+func writeJsonResponseStatus(w http.ResponseWriter, status int, schemaName string, v any) {
+	if config().ValidateResponses {
+		if err := validateResponse(schemaName, v); err != nil {
+			log.Printf("Warning: response does not match schema %s: %v", schemaName, err)
+		}
+	}
+	data, err := encodeJson(v)
+	if err != nil {
+		writeEncodingFailure(w, v, err)
+		return
+	}
+	if signedSchemas[schemaName] {
+		signManifestResponse(w, data)
+		tlog.Record(data)
+	}
+	writeJsonBytes(w, status, data)
+}
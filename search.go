@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	searchCacheTTL    = 5 * time.Minute
+	maxSearchResults  = 50
+	maxSearchQueryLen = 64
+)
+
+type searchResult struct {
+	Version string `json:"version"`
+	PubDate string `json:"pubDate,omitempty"`
+	// Unavailable marks versions whose files the last verification sweep
+	// could not download or that failed their checksum.
+	Unavailable bool `json:"unavailable,omitempty"`
+	// Libraries lists the dependencies added, upgraded or removed since the
+	// release this one replaced, once recorded.
+	Libraries *libraryChanges `json:"libraries,omitempty"`
+	// repository is where the version was found, which link rot is
+	// tracked by.
+	repository string
+}
+
+var searchCache = newNamedLruCache[string, []searchResult]("search", 128)
+
+func listArtifactVersions(artifact ArtifactConfig) ([]searchResult, error) {
+	cacheKey := artifact.Group + ":" + artifact.Artifact
+	if results, ok := searchCache.Get(cacheKey); ok {
+		return results, nil
+	}
+	seen := make(map[string]bool)
+	var results []searchResult
+	for _, repo := range artifactBranches(artifact) {
+		if seen[repo] {
+			continue
+		}
+		seen[repo] = true
+		items, err := listNexusVersions(repo, artifact.Group, artifact.Artifact)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if slices.ContainsFunc(results, func(r searchResult) bool { return r.Version == item.Version }) {
+				continue
+			}
+			result := searchResult{Version: item.Version, repository: repo}
+			if asset, ok := item.findRole(mergeAssetSelectors(artifact.Assets), rolePrimary); ok {
+				result.PubDate = normalizeTimestamp(asset.LastModified)
+			}
+			results = append(results, result)
+		}
+	}
+	slices.SortFunc(results, func(a, b searchResult) int {
+		return compareVersions(b.Version, a.Version)
+	})
+	searchCache.SetWithTTL(cacheKey, results, searchCacheTTL)
+	return results, nil
+}
+
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	if mirror != nil {
+		mirror.proxy.ServeHTTP(w, r)
+		return
+	}
+	query := r.URL.Query()
+	artifact, err := artifacts.Lookup(query.Get("artifact"))
+	if err != nil {
+		writeFailure(w, "", err)
+		return
+	}
+	q := strings.ToLower(query.Get("q"))
+	if len(q) > maxSearchQueryLen {
+		http.Error(w, "Query too long", http.StatusBadRequest)
+		return
+	}
+	versions, err := listArtifactVersions(artifact)
+	if err != nil {
+		writeFailure(w, "Failed to list versions", err)
+		return
+	}
+	results := []searchResult{}
+	for _, v := range versions {
+		if strings.Contains(strings.ToLower(v.Version), q) {
+			_, v.Unavailable = linkRot.Problem(v.repository, artifact.Group+":"+artifact.Artifact, v.Version)
+			if changes, ok := libraryChangeLog.Get(artifact.Group+":"+artifact.Artifact, v.Version); ok {
+				v.Libraries = &changes
+			}
+			results = append(results, v)
+			if len(results) == maxSearchResults {
+				break
+			}
+		}
+	}
+	writeJsonResponse(w, "search", results)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+type settlingObservation struct {
+	fingerprint string
+	since       time.Time
+}
+
+// settlingVersions remembers when each version's asset set was last seen to
+// change, so versions that CI is still uploading to are not advertised yet.
+var settlingVersions = newNamedLruCache[string, settlingObservation]("settling", 1024)
+
+func assetFingerprint(item nexusItem) string {
+	parts := make([]string, 0, len(item.Assets))
+	for _, asset := range item.Assets {
+		parts = append(parts, asset.Maven2.Classifier+"."+asset.Maven2.Extension+":"+asset.Checksum.Sha1)
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// settlingRequiredAssets are the assets a version must have before it can
+// settle, unless its product configures requiredAssets: the jar and the
+// libraries.json CI uploads after it.
+var settlingRequiredAssets = []string{rolePrimary, roleLibraries}
+
+// isSettled reports whether a version has all its required assets and they
+// have been stable for the settling period, either because every asset was
+// uploaded longer ago than that or because we have watched the asset set stay
+// unchanged for that long.
+func isSettled(key string, item nexusItem, ch channel, period time.Duration) bool {
+	if period <= 0 {
+		return true
+	}
+	required := ch.RequiredAssets
+	if required == nil {
+		required = settlingRequiredAssets
+	}
+	if len(missingAssets(item, ch.Assets, required)) > 0 {
+		return false
+	}
+	now := clock.Now()
+	newest := time.Time{}
+	for _, asset := range item.Assets {
+		modified, ok := parseTimestamp(asset.LastModified)
+		if !ok {
+			newest = now
+			break
+		}
+		if modified.After(newest) {
+			newest = modified
+		}
+	}
+	if !newest.IsZero() && now.Sub(newest) >= period {
+		return true
+	}
+	fingerprint := assetFingerprint(item)
+	observed, ok := settlingVersions.Get(key)
+	if !ok || observed.fingerprint != fingerprint {
+		settlingVersions.Set(key, settlingObservation{fingerprint: fingerprint, since: now})
+		return false
+	}
+	return now.Sub(observed.since) >= period
+}
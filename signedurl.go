@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const maxSignedUrlTTL = 7 * 24 * time.Hour
+
+var urlSigningKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+func urlSignature(path string, expires int64) string {
+	mac := hmac.New(sha256.New, urlSigningKey)
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signUrl returns path with an expiry and HMAC signature attached, granting
+// access to a private channel's file until then.
+func signUrl(path string, ttl time.Duration) (string, time.Time) {
+	expires := clock.Now().Add(ttl).Truncate(time.Second)
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires.Unix(), 10)},
+		"signature": {urlSignature(path, expires.Unix())},
+	}
+	return path + "?" + query.Encode(), expires
+}
+
+func validUrlSignature(r *http.Request) bool {
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || clock.Now().After(time.Unix(expires, 0)) {
+		return false
+	}
+	expected := urlSignature(r.URL.Path, expires)
+	return hmac.Equal([]byte(query.Get("signature")), []byte(expected))
+}
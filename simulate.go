@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// simulatedClient describes a launcher by the attributes that change what
+// latest.json serves it. Legacy launchers predate capability negotiation
+// and get the v1 manifest.
+type simulatedClient struct {
+	ClientId     string   `json:"clientId,omitempty"`
+	Os           string   `json:"os,omitempty"`
+	Arch         string   `json:"arch,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+	Compact      bool     `json:"compact,omitempty"`
+	Legacy       bool     `json:"legacy,omitempty"`
+}
+
+// simulation is the response a simulated client would receive, with the
+// release rules of the channel that decided it.
+type simulation struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body"`
+	Pinned  string            `json:"pinned,omitempty"`
+	Slots   *slotSummary      `json:"slots,omitempty"`
+	Rollout *rollout          `json:"rollout,omitempty"`
+	// RolloutBucket is the client's bucket out of 100; clients below the
+	// rollout percentage get its version.
+	RolloutBucket *int `json:"rolloutBucket,omitempty"`
+}
+
+// simulateClient runs the update check of latest.json for a made-up client,
+// so it answers as the real endpoint would, without counting it as a
+// check-in. Response signatures are left out.
+func simulateClient(ch channel, c simulatedClient) simulation {
+	query := url.Values{}
+	if c.Os != "" {
+		query.Set("os", c.Os)
+	}
+	if c.Arch != "" {
+		query.Set("arch", c.Arch)
+	}
+	if c.Compact {
+		query.Set("compact", "1")
+	}
+	r := &http.Request{Method: http.MethodGet, URL: &url.URL{Path: "/" + ch.Key() + "/latest.json", RawQuery: query.Encode()}, Header: make(http.Header)}
+	if c.ClientId != "" {
+		r.Header.Set(clientIdHeader, c.ClientId)
+	}
+	if !c.Legacy {
+		r.Header.Set(capabilitiesHeader, strings.Join(c.Capabilities, ","))
+	}
+
+	result := simulation{Headers: make(map[string]string), Slots: releaseSlots.Summary(ch.Key())}
+	if resp, retired := retiredChannel([]string{ch.Product, ch.Branch, "latest.json"}); retired {
+		result.Status, result.Body = http.StatusGone, resp
+	} else if p, err := parsePlatform(r); err != nil {
+		result.Status, result.Body = http.StatusBadRequest, err.Error()
+	} else if m, err := latestResponse(r, ch, p, nil); err != nil {
+		f := classifyFailure(err)
+		result.Status, result.Body = f.Status, failureResponse{Error: err.Error(), Reason: f.Reason}
+	} else {
+		result.Status, result.Body = http.StatusOK, m.Body
+		for name, values := range m.Header {
+			result.Headers[name] = strings.Join(values, ", ")
+		}
+	}
+	result.Pinned, _ = admin.Pin(ch.Key())
+	if ro, ok := rollouts.Get(ch.Key()); ok {
+		result.Rollout = &ro
+		if c.ClientId != "" {
+			bucket := rolloutBucket(ch.Key(), ro.Version, c.ClientId)
+			result.RolloutBucket = &bucket
+		}
+	}
+	return result
+}
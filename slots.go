@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// manifestSlots are a channel's blue/green slots. Current is served as is,
+// without resolving, and Next holds a release prepared ahead of a switch.
+// Switching swaps them, so switching again reverts instantly.
+type manifestSlots struct {
+	Current UpdaterResponse  `json:"current"`
+	Next    *UpdaterResponse `json:"next,omitempty"`
+}
+
+// slotSummary is what the admin status shows of a channel's slots.
+type slotSummary struct {
+	Current string `json:"current"`
+	Next    string `json:"next,omitempty"`
+}
+
+type slotStore struct {
+	mu    sync.RWMutex
+	path  string
+	slots map[string]manifestSlots
+}
+
+func newSlotStore(path string) *slotStore {
+	return &slotStore{path: path, slots: make(map[string]manifestSlots)}
+}
+
+var releaseSlots = newSlotStore("")
+
+var (
+	errNoSlots    = errors.New("Channel has no slots")
+	errNoNextSlot = errors.New("Channel has no prepared release to switch to")
+	// errChannelSlotted refuses pins and rollbacks of a slotted channel:
+	// its current slot takes precedence, so they would not take effect
+	// until it is unslotted.
+	errChannelSlotted = errors.New("Channel is served from slots, prepare and switch instead or unslot it first")
+)
+
+func (s *slotStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.slots)
+}
+
+func (s *slotStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+	return writeJsonFile(s.path, s.slots)
+}
+
+func (s *slotStore) Current(channel string) (UpdaterResponse, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	slots, ok := s.slots[channel]
+	return slots.Current, ok
+}
+
+func (s *slotStore) Summary(channel string) *slotSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	slots, ok := s.slots[channel]
+	if !ok {
+		return nil
+	}
+	summary := &slotSummary{Current: slots.Current.Version}
+	if slots.Next != nil {
+		summary.Next = slots.Next.Version
+	}
+	return summary
+}
+
+// Prepare fills the next slot. The first preparation of a channel also
+// freezes what it serves into the current slot, so from then on releases
+// only go out by switching. The change applies in memory even if it cannot
+// be persisted.
+func (s *slotStore) Prepare(channel string, current, next UpdaterResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slots, ok := s.slots[channel]
+	if !ok {
+		current.Stale = false
+		slots.Current = current
+	}
+	slots.Next = &next
+	s.slots[channel] = slots
+	return s.persist()
+}
+
+// Switch swaps the current and next slots and returns the new current
+// manifest.
+func (s *slotStore) Switch(channel string) (UpdaterResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slots, ok := s.slots[channel]
+	if !ok || slots.Next == nil {
+		return UpdaterResponse{}, errNoNextSlot
+	}
+	previous := slots.Current
+	slots.Current, slots.Next = *slots.Next, &previous
+	s.slots[channel] = slots
+	return slots.Current, s.persist()
+}
+
+// Clear drops a channel's slots, returning it to regular resolution.
+func (s *slotStore) Clear(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.slots[channel]; !ok {
+		return errNoSlots
+	}
+	delete(s.slots, channel)
+	return s.persist()
+}
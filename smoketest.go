@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+type SmokeTestConfig struct {
+	Disabled bool `json:"disabled"`
+	// MaxLibraryChangePercent bounds how much the number of libraries may
+	// change from one manifest to the next.
+	MaxLibraryChangePercent int `json:"maxLibraryChangePercent"`
+}
+
+var smokeTestClient = &http.Client{Timeout: 10 * time.Second}
+
+// smokeTestRetryInterval is how long a failed verdict stands before the
+// version is tested again, so a download that was briefly unreachable does
+// not hold a release until it is pinned.
+const smokeTestRetryInterval = 15 * time.Minute
+
+// smokeTestResult is the verdict on a version: pending while its job runs.
+type smokeTestResult struct {
+	done   bool
+	err    error
+	tested time.Time
+}
+
+// smokeTestResults caches the verdict per "product/branch@version", so a
+// release is tested once in the job pool rather than on every resolve.
+var smokeTestResults = struct {
+	sync.Mutex
+	results map[string]smokeTestResult
+}{results: make(map[string]smokeTestResult)}
+
+// smokeTestVerdict returns the verdict on next, starting its smoke test in
+// the job pool if there is none yet, or running it right away when the pool
+// has no workers. Until the test is done the previous
+// manifest keeps being served; the job then drops the channel's cached
+// manifest, so the next update check picks up the verdict.
+func smokeTestVerdict(ch channel, prev, next UpdaterResponse) (done bool, err error) {
+	key := ch.Key() + "@" + next.Version
+	smokeTestResults.Lock()
+	result, ok := smokeTestResults.results[key]
+	if ok && result.err != nil && clock.Now().Sub(result.tested) > smokeTestRetryInterval {
+		ok = false
+	}
+	if !ok {
+		smokeTestResults.results[key] = smokeTestResult{}
+	}
+	smokeTestResults.Unlock()
+	if ok {
+		return result.done, result.err
+	}
+	run := func() error {
+		err := smokeTest(ch, prev, next)
+		if errors.Is(err, errUpstreamBudgetExceeded) {
+			// Not a verdict on the release, so it is tested again on the
+			// next resolve rather than held back.
+			smokeTestResults.Lock()
+			delete(smokeTestResults.results, key)
+			smokeTestResults.Unlock()
+			return err
+		}
+		smokeTestResults.Lock()
+		smokeTestResults.results[key] = smokeTestResult{done: true, err: err, tested: clock.Now()}
+		smokeTestResults.Unlock()
+		recordSmokeTest(ch.Key(), next.Version, err)
+		manifestCache.Delete(ch.Key())
+		return err
+	}
+	// Subcommands such as export resolve before the pool is started, and
+	// would otherwise wait on a job no worker ever runs.
+	if !jobs.Running() {
+		err := run()
+		return true, err
+	}
+	if !jobs.Submit("smoke-test", run) {
+		smokeTestResults.Lock()
+		delete(smokeTestResults.results, key)
+		smokeTestResults.Unlock()
+	}
+	return false, nil
+}
+
+// smokeTest compares a freshly resolved manifest with the one served before
+// it. A version going backwards, a large swing in the number of libraries
+// or a download that does not resolve usually means a broken publish, not
+// a release, so the previous manifest keeps being served until the new one
+// is pinned explicitly. Callers skip the test when the previous version has
+// been blocked, since there is nothing to fall back to then.
+func smokeTest(ch channel, prev, next UpdaterResponse) error {
+	cfg := config().SmokeTest
+	if cfg.Disabled {
+		return nil
+	}
+	if compareVersions(next.Version, prev.Version) < 0 {
+		return fmt.Errorf("Version went back from %s to %s", prev.Version, next.Version)
+	}
+	if before, after := len(prev.Libraries), len(next.Libraries); before > 0 && cfg.MaxLibraryChangePercent > 0 {
+		if change := max(after-before, before-after) * 100 / before; change > cfg.MaxLibraryChangePercent {
+			return fmt.Errorf("Library count changed from %d to %d", before, after)
+		}
+	}
+	urls := []string{next.Url}
+	for _, url := range next.Libraries {
+		urls = append(urls, url)
+	}
+	slices.Sort(urls)
+	for _, url := range urls {
+		if url == prev.Url || containsValue(prev.Libraries, url) {
+			continue
+		}
+		if err := checkResolvable(url); err != nil {
+			return fmt.Errorf("%s does not resolve: %w", url, err)
+		}
+	}
+	// A size that cannot be measured does not hold the release, as the
+	// downloads themselves resolved.
+	reason, err := downloadGrowth(ch.Group+":"+ch.Artifact, prev, next)
+	if err != nil {
+		log.Printf("Warning: failed to measure the download of %s %s: %v", ch.Key(), next.Version, err)
+	} else if reason != "" {
+		return fmt.Errorf("Size regression, %s", reason)
+	}
+	return nil
+}
+
+func containsValue(m map[string]string, value string) bool {
+	for _, v := range m {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkResolvable only asks for the headers, as the files are not needed.
+func checkResolvable(url string) error {
+	resp, err := downloadDo(smokeTestClient, http.MethodHead, url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HEAD returned %s", resp.Status)
+	}
+	return nil
+}
+
+// smokeTestHalts remembers which version each channel was held back from,
+// so a failing release alerts once rather than on every cache expiry.
+var smokeTestHalts = struct {
+	sync.Mutex
+	versions map[string]string
+}{versions: make(map[string]string)}
+
+func recordSmokeTest(channel, version string, err error) {
+	smokeTestHalts.Lock()
+	if err == nil {
+		delete(smokeTestHalts.versions, channel)
+		smokeTestHalts.Unlock()
+		return
+	}
+	alerted := smokeTestHalts.versions[channel] == version
+	smokeTestHalts.versions[channel] = version
+	smokeTestHalts.Unlock()
+	if alerted {
+		return
+	}
+	events.Publish(Event{Type: EventRolloutHalted, Channel: channel, Version: version, Reason: err.Error()})
+}
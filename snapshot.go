@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+)
+
+type manifestSnapshot struct {
+	mu        sync.Mutex
+	path      string
+	manifests map[string]UpdaterResponse
+}
+
+func newManifestSnapshot(path string) *manifestSnapshot {
+	return &manifestSnapshot{path: path, manifests: make(map[string]UpdaterResponse)}
+}
+
+func (s *manifestSnapshot) Load() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readJsonFile(s.path, &s.manifests)
+}
+
+func (s *manifestSnapshot) Get(branch string) (UpdaterResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, ok := s.manifests[branch]
+	return resp, ok
+}
+
+func (s *manifestSnapshot) Update(branch string, resp UpdaterResponse) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.manifests[branch]; ok && reflect.DeepEqual(prev, resp) {
+		return false, nil
+	}
+	s.manifests[branch] = resp
+	if s.path == "" {
+		return true, nil
+	}
+	return true, writeJsonFile(s.path, s.manifests)
+}
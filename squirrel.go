@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Electron's autoUpdater speaks Squirrel. Squirrel.Windows fetches
+// /{product}/{branch}/squirrel/RELEASES and then the package it names
+// relative to it; Squirrel.Mac asks /{product}/{branch}/squirrel/darwin.json
+// with ?version= and gets 204 when up to date. The packages are the assets
+// of the "squirrel-windows" (full .nupkg) and "squirrel-darwin" (.zip) roles.
+const (
+	roleSquirrelWindows = "squirrel-windows"
+	roleSquirrelDarwin  = "squirrel-darwin"
+)
+
+type squirrelPackage struct {
+	Sha1 string
+	Size int64
+}
+
+type squirrelDarwinUpdate struct {
+	Url     string `json:"url"`
+	Name    string `json:"name"`
+	Notes   string `json:"notes,omitempty"`
+	PubDate string `json:"pub_date,omitempty"`
+}
+
+// squirrelPackages keeps the digest of each package, which RELEASES needs
+// and Nexus does not report for every repository. Launchers checking in
+// together after a release share one download of the package.
+var squirrelPackages = newMemoizer[string, squirrelPackage]("squirrelPackages", 64)
+
+func squirrelHandler(w http.ResponseWriter, r *http.Request, ch channel, file string) {
+	recordCheckIn(ch)
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	resp = applyRollout(w.Header(), r, ch, resp)
+	markStale(w.Header(), resp)
+	switch file {
+	case "RELEASES":
+		squirrelReleasesHandler(w, ch, resp)
+	case "darwin.json":
+		squirrelDarwinHandler(w, r, ch, resp)
+	default:
+		if url, ok := resp.Assets[roleSquirrelWindows]; ok && file == path.Base(url) {
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func squirrelReleasesHandler(w http.ResponseWriter, ch channel, resp UpdaterResponse) {
+	url, ok := resp.Assets[roleSquirrelWindows]
+	if !ok {
+		http.Error(w, "No Squirrel.Windows package published", http.StatusNotFound)
+		return
+	}
+	pkg, err := fetchSquirrelPackage(url)
+	if err != nil {
+		writeFailure(w, "Failed to fetch Squirrel package", err)
+		return
+	}
+	writeBody(w, textContentType, []byte(fmt.Sprintf("%s %s %d\n", strings.ToUpper(pkg.Sha1), path.Base(url), pkg.Size)))
+}
+
+func squirrelDarwinHandler(w http.ResponseWriter, r *http.Request, ch channel, resp UpdaterResponse) {
+	url, ok := resp.Assets[roleSquirrelDarwin]
+	if !ok {
+		http.Error(w, "No Squirrel.Mac package published", http.StatusNotFound)
+		return
+	}
+	if current := r.URL.Query().Get("version"); current != "" && compareVersions(current, resp.Version) >= 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	update := squirrelDarwinUpdate{Url: url, Name: resp.Version, PubDate: resp.PubDate}
+	if notesUrl, ok := resp.Assets[roleNotes]; ok {
+		if notes, err := fetchReleaseNotes(ch.Group+":"+ch.Artifact+":"+resp.Version, notesUrl); err == nil {
+			update.Notes = notes
+		} else {
+			log.Printf("Warning: failed to fetch release notes for %s: %v", resp.Version, err)
+		}
+	}
+	writeJsonResponse(w, "squirrel-darwin", update)
+}
+
+// fetchSquirrelPackage digests a package once; a released package does not
+// change, so the result does not expire.
+func fetchSquirrelPackage(url string) (squirrelPackage, error) {
+	return squirrelPackages.Do(url, 0, func() (squirrelPackage, error) {
+		return digestSquirrelPackage(url)
+	})
+}
+
+func digestSquirrelPackage(url string) (squirrelPackage, error) {
+	resp, err := downloadDo(bundleClient, http.MethodGet, url)
+	if err != nil {
+		return squirrelPackage{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return squirrelPackage{}, fmt.Errorf("Failed to fetch package: %w", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode})
+	}
+	hash := sha1.New()
+	size, err := io.Copy(hash, resp.Body)
+	if err != nil {
+		return squirrelPackage{}, err
+	}
+	return squirrelPackage{Sha1: hex.EncodeToString(hash.Sum(nil)), Size: size}, nil
+}
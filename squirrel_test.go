@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSquirrelPackageFetchedOnce(t *testing.T) {
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("nupkg"))
+	}))
+	t.Cleanup(server.Close)
+	url := server.URL + "/selene-client-1.2.0-full.nupkg"
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pkg, err := fetchSquirrelPackage(url)
+			if err != nil || pkg.Size != 5 {
+				t.Errorf("fetchSquirrelPackage = %+v, %v", pkg, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := fetchSquirrelPackage(url); err != nil {
+		t.Fatalf("fetchSquirrelPackage: %v", err)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("package downloaded %d times, want 1", n)
+	}
+}
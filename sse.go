@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// sseKeepAlive is how often an idle event stream sends a comment, so proxies
+// and load balancers do not close it.
+const sseKeepAlive = 30 * time.Second
+
+// releaseEvent is the data of a "release" server-sent event.
+type releaseEvent struct {
+	Channel         string `json:"channel"`
+	Version         string `json:"version"`
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	ManifestUrl     string `json:"manifestUrl"`
+}
+
+// eventsHandler streams a "release" event with the version the channel
+// advertises, then another whenever that version changes, so running
+// launchers learn about updates without polling. The event id is the
+// version, so a reconnecting client that already has it is not told again.
+func eventsHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	updates, stop := releaseWatches.Watch(ch.Key())
+	defer stop()
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	version := resp.Version
+	if r.Header.Get("Last-Event-ID") != version {
+		if err := writeReleaseEvent(w, ch, version, ""); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e := <-updates:
+			if e.Type == EventReleaseYanked {
+				// Resolve the replacement right away, as listening
+				// launchers will not request it.
+				resp, err := resolveChannel(ch)
+				if err != nil {
+					log.Printf("Warning: failed to resolve %s after a yank: %v", ch.Key(), err)
+					continue
+				}
+				e.Version = resp.Version
+			}
+			if e.Version == version {
+				continue
+			}
+			if err := writeReleaseEvent(w, ch, e.Version, version); err != nil {
+				return
+			}
+			version = e.Version
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeReleaseEvent(w http.ResponseWriter, ch channel, version, previous string) error {
+	event := releaseEvent{
+		Channel:         ch.Key(),
+		Version:         version,
+		PreviousVersion: previous,
+		ManifestUrl:     "/" + ch.Key() + "/latest.json",
+	}
+	if config().ValidateResponses {
+		if err := validateResponse("release-event", event); err != nil {
+			log.Printf("Warning: response does not match schema release-event: %v", err)
+		}
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: release\nid: %s\ndata: %s\n\n", version, data)
+	return err
+}
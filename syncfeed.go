@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The sync feed is the replication protocol between a primary and its
+// replicas: every manifest change becomes an entry with a sequence number,
+// the hash of the previous entry and an Ed25519 signature by the primary.
+// Replicas pin the primary's public key and re-serve entries verbatim, so a
+// compromised mirror can neither alter, reorder nor drop manifests without
+// downstream replicas noticing.
+
+const maxSyncPageSize = 100
+
+// syncEntry keeps the manifest as the primary encoded it, so replicas verify
+// and re-serve the exact bytes that were signed, even when their build knows
+// a different set of manifest fields.
+type syncEntry struct {
+	Seq       int64           `json:"seq"`
+	Channel   string          `json:"channel"`
+	Manifest  json.RawMessage `json:"manifest"`
+	Prev      string          `json:"prev,omitempty"`
+	Signature string          `json:"signature"`
+}
+
+func (e syncEntry) signedBytes() []byte {
+	data, _ := json.Marshal(struct {
+		Seq      int64           `json:"seq"`
+		Channel  string          `json:"channel"`
+		Manifest json.RawMessage `json:"manifest"`
+		Prev     string          `json:"prev,omitempty"`
+	}{e.Seq, e.Channel, e.Manifest, e.Prev})
+	return data
+}
+
+func (e syncEntry) hash() string {
+	sum := sha256.Sum256(e.signedBytes())
+	return hex.EncodeToString(sum[:])
+}
+
+type syncFeed struct {
+	mu      sync.RWMutex
+	path    string
+	key     ed25519.PrivateKey
+	public  ed25519.PublicKey
+	entries []syncEntry
+}
+
+// manifestFeed is the primary's or the replica's feed, nil unless one is
+// configured.
+var manifestFeed *syncFeed
+
+// manifestSigningKey is the primary's feed key, also used to sign offline
+// bundles. It is nil unless syncSigningKey is configured.
+var manifestSigningKey ed25519.PrivateKey
+
+// newSigningSyncFeed creates the primary's feed from a base64 Ed25519 seed.
+func newSigningSyncFeed(path, seed string) (*syncFeed, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("syncSigningKey must be a base64 encoded %d byte Ed25519 seed", ed25519.SeedSize)
+	}
+	key := ed25519.NewKeyFromSeed(raw)
+	return &syncFeed{path: path, key: key, public: key.Public().(ed25519.PublicKey)}, nil
+}
+
+// newReplicaSyncFeed creates a feed that only accepts entries signed by the
+// given base64 public key.
+func newReplicaSyncFeed(path, publicKey string) (*syncFeed, error) {
+	raw, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Mirror publicKey must be a base64 encoded Ed25519 public key")
+	}
+	return &syncFeed{path: path, public: raw}, nil
+}
+
+func (f *syncFeed) Load() error {
+	if f.path == "" {
+		return nil
+	}
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	var entries []syncEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 4<<20)
+	for scanner.Scan() {
+		var e syncEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("Invalid sync feed entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Entries on disk are verified like entries from the network, so a
+	// tampered local file is caught too.
+	return f.accept(entries)
+}
+
+func (f *syncFeed) persist(entries []syncEntry) error {
+	if f.path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		data, _ := json.Marshal(e)
+		if _, err = file.Write(append(data, '\n')); err != nil {
+			break
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (f *syncFeed) last() (int64, string) {
+	if len(f.entries) == 0 {
+		return 0, ""
+	}
+	e := f.entries[len(f.entries)-1]
+	return e.Seq, e.hash()
+}
+
+func (f *syncFeed) LastSeq() int64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	seq, _ := f.last()
+	return seq
+}
+
+// Append signs a manifest change and adds it to the primary's feed.
+func (f *syncFeed) Append(channel string, manifest UpdaterResponse) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seq, prev := f.last()
+	e := syncEntry{Seq: seq + 1, Channel: channel, Manifest: data, Prev: prev}
+	e.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(f.key, e.signedBytes()))
+	if err := f.persist([]syncEntry{e}); err != nil {
+		return err
+	}
+	f.entries = append(f.entries, e)
+	return nil
+}
+
+// Accept verifies entries received from upstream and appends them. Any gap,
+// broken hash chain or bad signature rejects the whole batch.
+func (f *syncFeed) Accept(entries []syncEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.accept(entries); err != nil {
+		return err
+	}
+	return f.persist(entries)
+}
+
+func (f *syncFeed) accept(entries []syncEntry) error {
+	seq, prev := f.last()
+	for _, e := range entries {
+		if e.Seq != seq+1 {
+			return fmt.Errorf("Sync entry %d out of sequence, expected %d", e.Seq, seq+1)
+		}
+		if e.Prev != prev {
+			return fmt.Errorf("Sync entry %d does not chain to entry %d", e.Seq, seq)
+		}
+		signature, err := base64.StdEncoding.DecodeString(e.Signature)
+		if err != nil || !ed25519.Verify(f.public, e.signedBytes(), signature) {
+			return fmt.Errorf("Sync entry %d has an invalid signature", e.Seq)
+		}
+		seq, prev = e.Seq, e.hash()
+	}
+	f.entries = append(f.entries, entries...)
+	return nil
+}
+
+// Manifests returns the manifests the feed recorded for a channel by
+// version, the latest entry for a version winning.
+func (f *syncFeed) Manifests(channel string) map[string]UpdaterResponse {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	manifests := make(map[string]UpdaterResponse)
+	for _, e := range f.entries {
+		if e.Channel != channel {
+			continue
+		}
+		var manifest UpdaterResponse
+		if err := json.Unmarshal(e.Manifest, &manifest); err != nil {
+			log.Printf("Warning: sync entry %d has an invalid manifest: %v", e.Seq, err)
+			continue
+		}
+		manifests[manifest.Version] = manifest
+	}
+	return manifests
+}
+
+// Since returns up to limit entries after the given sequence number.
+func (f *syncFeed) Since(after int64, limit int) []syncEntry {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	i, _ := slices.BinarySearchFunc(f.entries, after+1, func(e syncEntry, seq int64) int {
+		return int(e.Seq - seq)
+	})
+	return slices.Clone(f.entries[i:min(len(f.entries), i+limit)])
+}
+
+func (f *syncFeed) Subscribe(bus *eventBus) {
+	bus.Subscribe(func(e Event) {
+		if e.Type != EventManifestUpdated || slices.Contains(config().PrivateChannels, e.Channel) {
+			return
+		}
+		if err := f.Append(e.Channel, *e.Manifest); err != nil {
+			log.Printf("Warning: failed to append %s to sync feed: %v", e.Channel, err)
+		}
+	})
+}
+
+type syncPage struct {
+	Entries []syncEntry `json:"entries"`
+	LastSeq int64       `json:"lastSeq"`
+}
+
+func (f *syncFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/sync/") {
+	case "key":
+		writeAdminJson(w, map[string]string{"algorithm": "ed25519", "publicKey": base64.StdEncoding.EncodeToString(f.public)})
+	case "feed":
+		query := r.URL.Query()
+		after, err := strconv.ParseInt(query.Get("after"), 10, 64)
+		if query.Get("after") == "" {
+			after, err = 0, nil
+		}
+		if err != nil || after < 0 {
+			http.Error(w, "Invalid after", http.StatusBadRequest)
+			return
+		}
+		limit := maxSyncPageSize
+		if raw := query.Get("limit"); raw != "" {
+			if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxSyncPageSize {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+		writeAdminJson(w, syncPage{Entries: f.Since(after, limit), LastSeq: f.LastSeq()})
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Tauri launchers read /{product}/{branch}/tauri.json. Each bundle is an
+// asset role named "tauri-{os}-{arch}", e.g. "tauri-windows-x86_64" with
+// {"classifier": "windows-x86_64", "extension": "msi.zip"}, and its minisign
+// signature is the file Tauri writes next to it, uploaded with ".sig" appended
+// to the extension.
+const tauriRolePrefix = "tauri-"
+
+type tauriPlatform struct {
+	Signature string `json:"signature"`
+	Url       string `json:"url"`
+}
+
+type tauriManifest struct {
+	Version   string                   `json:"version"`
+	Notes     string                   `json:"notes,omitempty"`
+	PubDate   string                   `json:"pub_date,omitempty"`
+	Platforms map[string]tauriPlatform `json:"platforms"`
+}
+
+// bundleSignatures caches the detached signatures of native bundles, which
+// Tauri and Sparkle both expect next to the bundle with ".sig" appended.
+var bundleSignatures = newNamedLruCache[string, string]("bundleSignatures", 256)
+
+func tauriHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	recordCheckIn(ch)
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	resp = applyRollout(w.Header(), r, ch, resp)
+	markStale(w.Header(), resp)
+	manifest := tauriManifest{
+		Version:   resp.Version,
+		PubDate:   resp.PubDate,
+		Platforms: make(map[string]tauriPlatform),
+	}
+	for role, url := range resp.Assets {
+		target, ok := strings.CutPrefix(role, tauriRolePrefix)
+		if !ok {
+			continue
+		}
+		signature, err := fetchBundleSignature(url + ".sig")
+		if err != nil {
+			// Tauri rejects the whole manifest over one unsigned bundle.
+			log.Printf("Warning: leaving %s %s out of the Tauri manifest: %v", ch.Key(), target, err)
+			continue
+		}
+		manifest.Platforms[target] = tauriPlatform{Signature: signature, Url: url}
+	}
+	if len(manifest.Platforms) == 0 {
+		http.Error(w, "No Tauri bundles published", http.StatusNotFound)
+		return
+	}
+	if url, ok := resp.Assets[roleNotes]; ok {
+		if notes, err := fetchReleaseNotes(ch.Group+":"+ch.Artifact+":"+resp.Version, url); err == nil {
+			manifest.Notes = notes
+		} else {
+			log.Printf("Warning: failed to fetch release notes for %s: %v", resp.Version, err)
+		}
+	}
+	writeJsonResponse(w, "tauri", manifest)
+}
+
+func fetchBundleSignature(url string) (string, error) {
+	if signature, ok := bundleSignatures.Get(url); ok {
+		return signature, nil
+	}
+	resp, err := upstreamGet(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed to fetch signature: %w", &nexusStatusError{Status: resp.Status, StatusCode: resp.StatusCode})
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	signature := strings.TrimSpace(string(body))
+	bundleSignatures.Set(url, signature)
+	return signature, nil
+}
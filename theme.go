@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// ChannelTheme carries presentation hints for launchers' channel pickers.
+type ChannelTheme struct {
+	DisplayName string `json:"displayName,omitempty"`
+	// Color is a hex color such as "#e5a00d".
+	Color   string `json:"color,omitempty"`
+	IconUrl string `json:"iconUrl,omitempty"`
+	// Warning is shown before switching to the channel, e.g.
+	// "Experimental, may break saves".
+	Warning string `json:"warning,omitempty"`
+}
+
+var themeColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// channelTheme returns the theme configured for the channel, or failing that
+// for its branch.
+func channelTheme(ch channel) *ChannelTheme {
+	if theme, ok := config().Themes[ch.Key()]; ok {
+		return &theme
+	}
+	if theme, ok := config().Themes[ch.Branch]; ok {
+		return &theme
+	}
+	return nil
+}
+
+func validateChannelThemes(themes map[string]ChannelTheme) error {
+	for name, theme := range themes {
+		if theme.Color != "" && !themeColorPattern.MatchString(theme.Color) {
+			return fmt.Errorf("Theme %s has invalid color %q", name, theme.Color)
+		}
+		if theme.IconUrl != "" {
+			if u, err := url.Parse(theme.IconUrl); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("Theme %s has invalid iconUrl %q", name, theme.IconUrl)
+			}
+		}
+	}
+	return nil
+}
+
+func encodeThemeProto(theme *ChannelTheme) []byte {
+	var b []byte
+	b = appendProtoString(b, 1, theme.DisplayName)
+	b = appendProtoString(b, 2, theme.Color)
+	b = appendProtoString(b, 3, theme.IconUrl)
+	b = appendProtoString(b, 4, theme.Warning)
+	return b
+}
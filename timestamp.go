@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// upstreamTimestampLayouts are the formats Nexus has been seen to report,
+// tried in order.
+var upstreamTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+func parseTimestamp(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range upstreamTimestampLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// normalizeTimestamp re-emits an upstream timestamp as RFC 3339 in UTC, or
+// returns "" if it is empty or in an unrecognised format.
+func normalizeTimestamp(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	t, ok := parseTimestamp(raw)
+	if !ok {
+		log.Printf("Warning: unrecognised timestamp format %q", raw)
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
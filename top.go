@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+func fetchAdminStatus(server, token string) (adminStatus, error) {
+	var status adminStatus
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(server, "/")+"/admin/status", nil)
+	if err != nil {
+		return status, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("Admin API error: %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	return status, err
+}
+
+func runTop(args []string) error {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	server := fs.String("server", "http://localhost:8080", "base URL of the running update server")
+	token := fs.String("token", os.Getenv("SELENE_ADMIN_TOKEN"), "admin API token (defaults to $SELENE_ADMIN_TOKEN)")
+	interval := fs.Duration("interval", 2*time.Second, "refresh interval")
+	fs.Parse(args)
+
+	var prev adminStatus
+	var prevTime time.Time
+	for {
+		status, err := fetchAdminStatus(*server, *token)
+		now := time.Now()
+		var sb strings.Builder
+		sb.WriteString("\033[H\033[2J")
+		fmt.Fprintf(&sb, "selene-update-server  %s  %s\n\n", *server, now.Format(time.TimeOnly))
+		if err != nil {
+			fmt.Fprintf(&sb, "error: %v\n", err)
+		} else {
+			elapsed := now.Sub(prevTime).Seconds()
+			rate := func(cur, old int64) string {
+				if prevTime.IsZero() || elapsed <= 0 {
+					return "-"
+				}
+				return fmt.Sprintf("%.1f/s", float64(cur-old)/elapsed)
+			}
+			readyText := "ready"
+			if !status.Ready {
+				readyText = "warming up"
+			}
+			fmt.Fprintf(&sb, "status: %s   upstream: %d (%s)\n", readyText, status.UpstreamRequests, rate(status.UpstreamRequests, prev.UpstreamRequests))
+			fmt.Fprintf(&sb, "caches: manifests=%d libraries=%d negative=%d\n\n", status.Caches.Manifests, status.Caches.Libraries, status.Caches.Negative)
+
+			channels := make([]string, 0, len(status.Channels))
+			for key := range status.Channels {
+				channels = append(channels, key)
+			}
+			slices.Sort(channels)
+			fmt.Fprintf(&sb, "%-28s %-32s %-12s %-8s %s\n", "CHANNEL", "VERSION", "PINNED", "CACHED", "REQUESTS")
+			for _, key := range channels {
+				c := status.Channels[key]
+				fmt.Fprintf(&sb, "%-28s %-32s %-12s %-8t %d (%s)\n", key, c.Version, c.Pinned, c.Cached, c.Requests, rate(c.Requests, prev.Channels[key].Requests))
+			}
+			if len(status.Blocked) > 0 {
+				fmt.Fprintf(&sb, "\nblocked: %s\n", strings.Join(status.Blocked, ", "))
+			}
+			sb.WriteString("\nRECENT ERRORS\n")
+			if len(status.RecentErrors) == 0 {
+				sb.WriteString("  none\n")
+			}
+			for i := len(status.RecentErrors) - 1; i >= 0 && i >= len(status.RecentErrors)-10; i-- {
+				e := status.RecentErrors[i]
+				fmt.Fprintf(&sb, "  %s  %-28s %s\n", e.Time.Local().Format(time.TimeOnly), e.Channel, e.Message)
+			}
+			prev, prevTime = status, now
+		}
+		os.Stdout.WriteString(sb.String())
+		time.Sleep(*interval)
+	}
+}
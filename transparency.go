@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/bits"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// transparencyLog is an append-only RFC 6962 Merkle tree over every distinct
+// manifest response body the server has sent. Leaves hash the exact bytes
+// clients receive, so a client can check what it downloaded is in the log.
+type transparencyLog struct {
+	mu     sync.RWMutex
+	path   string
+	leaves [][]byte
+	// index maps each leaf hash to its position, so a body served again is
+	// not logged twice.
+	index map[string]int
+}
+
+func newTransparencyLog(path string) *transparencyLog {
+	return &transparencyLog{path: path, index: make(map[string]int)}
+}
+
+var tlog = newTransparencyLog("")
+
+func (t *transparencyLog) Load() error {
+	if t.path == "" {
+		return nil
+	}
+	f, err := os.Open(t.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		leaf, err := hex.DecodeString(strings.TrimSpace(scanner.Text()))
+		if err != nil || len(leaf) != sha256.Size {
+			return fmt.Errorf("Invalid transparency log entry %d", len(t.leaves))
+		}
+		t.index[string(leaf)] = len(t.leaves)
+		t.leaves = append(t.leaves, leaf)
+	}
+	return scanner.Err()
+}
+
+func merkleLeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func largestPowerOfTwoBelow(n int) int {
+	return 1 << (bits.Len(uint(n-1)) - 1)
+}
+
+func merkleTreeHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	k := largestPowerOfTwoBelow(len(leaves))
+	return merkleNodeHash(merkleTreeHash(leaves[:k]), merkleTreeHash(leaves[k:]))
+}
+
+func merkleInclusionPath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := largestPowerOfTwoBelow(len(leaves))
+	if m < k {
+		return append(merkleInclusionPath(m, leaves[:k]), merkleTreeHash(leaves[k:]))
+	}
+	return append(merkleInclusionPath(m-k, leaves[k:]), merkleTreeHash(leaves[:k]))
+}
+
+func merkleConsistencyProof(m int, leaves [][]byte, complete bool) [][]byte {
+	n := len(leaves)
+	if m == n {
+		if complete {
+			return nil
+		}
+		return [][]byte{merkleTreeHash(leaves)}
+	}
+	k := largestPowerOfTwoBelow(n)
+	if m <= k {
+		return append(merkleConsistencyProof(m, leaves[:k], complete), merkleTreeHash(leaves[k:]))
+	}
+	return append(merkleConsistencyProof(m-k, leaves[k:], false), merkleTreeHash(leaves[:k]))
+}
+
+func (t *transparencyLog) Append(data []byte) (int, error) {
+	leaf := merkleLeafHash(data)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if i, ok := t.index[string(leaf)]; ok {
+		return i, nil
+	}
+	if t.path != "" {
+		f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return 0, err
+		}
+		_, err = fmt.Fprintln(f, hex.EncodeToString(leaf))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	t.index[string(leaf)] = len(t.leaves)
+	t.leaves = append(t.leaves, leaf)
+	return len(t.leaves) - 1, nil
+}
+
+// Record logs a manifest response body unless it is already in the log.
+func (t *transparencyLog) Record(body []byte) {
+	t.mu.RLock()
+	_, ok := t.index[string(merkleLeafHash(body))]
+	t.mu.RUnlock()
+	if ok {
+		return
+	}
+	if _, err := t.Append(body); err != nil {
+		log.Printf("Warning: failed to append to transparency log: %v", err)
+	}
+}
+
+func hexHashes(hashes [][]byte) []string {
+	out := make([]string, len(hashes))
+	for i, h := range hashes {
+		out[i] = hex.EncodeToString(h)
+	}
+	return out
+}
+
+func writeTransparencyJson(w http.ResponseWriter, v any) {
+	writeJson(w, v)
+}
+
+func treeSizeParam(r *http.Request, name string, def, max int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 || n > max {
+		return 0, fmt.Errorf("Invalid %s", name)
+	}
+	return n, nil
+}
+
+func (t *transparencyLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t.mu.RLock()
+	leaves := t.leaves
+	t.mu.RUnlock()
+
+	switch strings.TrimPrefix(r.URL.Path, "/transparency/") {
+	case "sth":
+		writeTransparencyJson(w, map[string]any{
+			"treeSize": len(leaves),
+			"rootHash": hex.EncodeToString(merkleTreeHash(leaves)),
+		})
+	case "proof/inclusion":
+		treeSize, err := treeSizeParam(r, "treeSize", len(leaves), len(leaves))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		leaf, err := hex.DecodeString(r.URL.Query().Get("hash"))
+		if err != nil || len(leaf) != sha256.Size {
+			http.Error(w, "Invalid hash", http.StatusBadRequest)
+			return
+		}
+		for i := 0; i < treeSize; i++ {
+			if string(leaves[i]) == string(leaf) {
+				writeTransparencyJson(w, map[string]any{
+					"leafIndex": i,
+					"treeSize":  treeSize,
+					"auditPath": hexHashes(merkleInclusionPath(i, leaves[:treeSize])),
+				})
+				return
+			}
+		}
+		http.Error(w, "Hash not found in tree", http.StatusNotFound)
+	case "proof/consistency":
+		first, err := treeSizeParam(r, "first", len(leaves), len(leaves))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		second, err := treeSizeParam(r, "second", len(leaves), len(leaves))
+		if err != nil || first > second || first == 0 {
+			http.Error(w, "Invalid tree sizes", http.StatusBadRequest)
+			return
+		}
+		writeTransparencyJson(w, map[string]any{
+			"first":       first,
+			"second":      second,
+			"consistency": hexHashes(merkleConsistencyProof(first, leaves[:second], true)),
+		})
+	case "entries":
+		start, err := treeSizeParam(r, "start", 0, len(leaves))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		end, err := treeSizeParam(r, "end", len(leaves), len(leaves))
+		if err != nil || start > end {
+			http.Error(w, "Invalid range", http.StatusBadRequest)
+			return
+		}
+		writeTransparencyJson(w, map[string]any{"start": start, "entries": hexHashes(leaves[start:end])})
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Java launchers built on update4j read /{product}/{branch}/update4j.xml.
+// update4j verifies files by size and Adler-32 checksum, which Nexus does
+// not report, so every file is downloaded once to measure it.
+
+// update4jOs maps platform rule OS names to update4j's short names.
+var update4jOs = map[string]string{
+	"windows": "win",
+	"macos":   "mac",
+	"linux":   "linux",
+}
+
+type update4jFile struct {
+	Uri       string `xml:"uri,attr"`
+	Path      string `xml:"path,attr"`
+	Size      int64  `xml:"size,attr"`
+	Checksum  string `xml:"checksum,attr"`
+	Os        string `xml:"os,attr,omitempty"`
+	Classpath bool   `xml:"classpath,attr,omitempty"`
+}
+
+type update4jProperty struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type update4jConfig struct {
+	XMLName    xml.Name           `xml:"configuration"`
+	Timestamp  string             `xml:"timestamp,attr"`
+	Properties []update4jProperty `xml:"properties>property"`
+	Files      []update4jFile     `xml:"files>file"`
+}
+
+type update4jDigest struct {
+	Size     int64
+	Checksum string
+}
+
+var update4jDigests = newNamedLruCache[string, update4jDigest]("update4jDigests", 1024)
+
+func update4jHandler(w http.ResponseWriter, r *http.Request, ch channel) {
+	recordCheckIn(ch)
+	resp, err := resolveChannel(ch)
+	if err != nil {
+		writeFailure(w, "Failed to fetch latest version", err)
+		return
+	}
+	resp = applyRollout(w.Header(), r, ch, resp)
+	markStale(w.Header(), resp)
+	data, err := feeds.Do(ch.Key()+"/update4j.xml@"+resp.Version, manifestCacheTTL, func() ([]byte, error) {
+		return renderUpdate4jConfig(ch, resp)
+	})
+	if err != nil {
+		writeFailure(w, "Failed to build update4j configuration", err)
+		return
+	}
+	writeBody(w, xmlContentType, data)
+}
+
+// renderUpdate4jConfig lists the jar and its libraries, with natives
+// restricted to their OS by the channel's platform rules.
+func renderUpdate4jConfig(ch channel, resp UpdaterResponse) ([]byte, error) {
+	files := []update4jFile{{Uri: resp.Url, Path: resp.FileName, Classpath: true}}
+	for _, fileName := range slices.Sorted(maps.Keys(resp.Libraries)) {
+		file := update4jFile{Uri: resp.Libraries[fileName], Path: "libraries/" + fileName, Classpath: true}
+		for _, rule := range ch.Platforms {
+			if ok, _ := path.Match(rule.Pattern, fileName); ok {
+				file.Os = update4jOs[rule.Os]
+				break
+			}
+		}
+		files = append(files, file)
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	sem := make(chan struct{}, checksumFetchConcurrency)
+	for i := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			digest, err := fetchUpdate4jDigest(files[i].Uri)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", files[i].Uri, err)
+				}
+				return
+			}
+			files[i].Size, files[i].Checksum = digest.Size, digest.Checksum
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	timestamp := resp.PubDate
+	if timestamp == "" {
+		timestamp = clock.Now().UTC().Format(time.RFC3339)
+	}
+	cfg := update4jConfig{
+		Timestamp: timestamp,
+		Properties: []update4jProperty{
+			{Key: "selene.product", Value: ch.Product},
+			{Key: "selene.version", Value: resp.Version},
+		},
+		Files: files,
+	}
+	data, err := xml.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func fetchUpdate4jDigest(url string) (update4jDigest, error) {
+	if digest, ok := update4jDigests.Get(url); ok {
+		return digest, nil
+	}
+	resp, err := downloadDo(bundleClient, http.MethodGet, url)
+	if err != nil {
+		return update4jDigest{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return update4jDigest{}, fmt.Errorf("Downloading returned %s", resp.Status)
+	}
+	hash := adler32.New()
+	size, err := io.Copy(hash, resp.Body)
+	if err != nil {
+		return update4jDigest{}, err
+	}
+	digest := update4jDigest{Size: size, Checksum: strconv.FormatUint(uint64(hash.Sum32()), 16)}
+	update4jDigests.Set(url, digest)
+	return digest, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errUpstreamBudgetExceeded = errors.New("Upstream request budget exceeded")
+
+var (
+	upstreamRequests        = expvar.NewInt("upstream_requests")
+	upstreamBudgetRejected  = expvar.NewInt("upstream_budget_rejected")
+	upstreamOpenConnections = expvar.NewInt("upstream_open_connections")
+)
+
+// countedConn keeps upstreamOpenConnections in step with the connections the
+// upstream client holds open, idle keep-alive connections included.
+type countedConn struct {
+	net.Conn
+	closed atomic.Bool
+}
+
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		upstreamOpenConnections.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+var upstreamClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		upstreamOpenConnections.Add(1)
+		return &countedConn{Conn: conn}, nil
+	}
+	return &http.Client{Transport: transport}
+}()
+
+// requestBudget admits at most perMinute requests in any one-minute window.
+// It remembers when each admitted request was made rather than refilling
+// tokens, so a full budget cannot be spent once and then again as it
+// refills within the same minute.
+type requestBudget struct {
+	mu        sync.Mutex
+	perMinute int
+	admitted  []time.Time
+}
+
+func newRequestBudget(perMinute int) *requestBudget {
+	return &requestBudget{perMinute: perMinute}
+}
+
+func (b *requestBudget) Take() bool {
+	if b.perMinute <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := clock.Now()
+	b.expire(now)
+	if len(b.admitted) >= b.perMinute {
+		return false
+	}
+	b.admitted = append(b.admitted, now)
+	return true
+}
+
+// expire forgets requests made more than a minute before now.
+func (b *requestBudget) expire(now time.Time) {
+	n := 0
+	for n < len(b.admitted) && now.Sub(b.admitted[n]) >= time.Minute {
+		n++
+	}
+	b.admitted = b.admitted[n:]
+}
+
+// Remaining returns how many requests would be admitted right now and when
+// the budget is full again.
+func (b *requestBudget) Remaining() (int, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := clock.Now()
+	b.expire(now)
+	if len(b.admitted) == 0 {
+		return b.perMinute, now
+	}
+	return b.perMinute - len(b.admitted), b.admitted[len(b.admitted)-1].Add(time.Minute)
+}
+
+// RetryAfter returns how long until the next request would be admitted.
+func (b *requestBudget) RetryAfter() time.Duration {
+	if b.perMinute <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := clock.Now()
+	b.expire(now)
+	if len(b.admitted) < b.perMinute {
+		return 0
+	}
+	return b.admitted[0].Add(time.Minute).Sub(now)
+}
+
+var upstreamBudget = newRequestBudget(defaultConfig().Upstream.RequestsPerMinute)
+
+func upstreamGet(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return upstreamDo(upstreamClient, req)
+}
+
+// upstreamDo sends req to Nexus with client, counting it against
+// upstreamBudget and recording its outcome like every other Nexus request.
+func upstreamDo(client *http.Client, req *http.Request) (*http.Response, error) {
+	if !upstreamBudget.Take() {
+		upstreamBudgetRejected.Add(1)
+		return nil, errUpstreamBudgetExceeded
+	}
+	upstreamRequests.Add(1)
+	started := time.Now()
+	resp, err := client.Do(req)
+	outcome := "error"
+	nexusHealth.Record(err == nil && resp.StatusCode < 500)
+	if err == nil {
+		outcome = strconv.Itoa(resp.StatusCode/100) + "xx"
+	}
+	upstreamDurations.Observe(outcome, time.Since(started))
+	return resp, err
+}
+
+func isNexusUrl(url string) bool {
+	return strings.HasPrefix(url, config().Nexus.Url+"/")
+}
+
+// downloadDo fetches a release file with client. Files hosted on Nexus go
+// through upstreamDo, so checking and bundling downloads spends the same
+// budget as resolving; files hosted elsewhere are fetched directly.
+func downloadDo(client *http.Client, method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if isNexusUrl(url) {
+		return upstreamDo(client, req)
+	}
+	return client.Do(req)
+}
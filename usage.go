@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	usageHourlyRetention = 7 * 24 * time.Hour
+	usageDailyRetention  = 90 * 24 * time.Hour
+)
+
+type channelUsage struct {
+	hourly  map[int64]int64
+	daily   map[string]int64
+	heatmap [7][24]int64
+}
+
+// usageStats aggregates launcher check-ins per channel into hourly and daily
+// counters plus a weekday-by-hour heatmap (UTC), to find quiet publishing
+// windows.
+type usageStats struct {
+	mu       sync.Mutex
+	channels map[string]*channelUsage
+}
+
+func newUsageStats() *usageStats {
+	return &usageStats{channels: make(map[string]*channelUsage)}
+}
+
+func (u *usageStats) Record(channel string, t time.Time) {
+	t = t.UTC()
+	hour, day := t.Truncate(time.Hour).Unix(), t.Format(time.DateOnly)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	c, ok := u.channels[channel]
+	if !ok {
+		c = &channelUsage{hourly: make(map[int64]int64), daily: make(map[string]int64)}
+		u.channels[channel] = c
+	}
+	if _, ok := c.hourly[hour]; !ok {
+		c.prune(t)
+	}
+	c.hourly[hour]++
+	c.daily[day]++
+	c.heatmap[t.Weekday()][t.Hour()]++
+}
+
+func (c *channelUsage) prune(now time.Time) {
+	hourCutoff := now.Add(-usageHourlyRetention).Unix()
+	for hour := range c.hourly {
+		if hour < hourCutoff {
+			delete(c.hourly, hour)
+		}
+	}
+	dayCutoff := now.Add(-usageDailyRetention).Format(time.DateOnly)
+	for day := range c.daily {
+		if day < dayCutoff {
+			delete(c.daily, day)
+		}
+	}
+}
+
+type usageBucket struct {
+	Start string `json:"start"`
+	Count int64  `json:"count"`
+}
+
+type channelUsageReport struct {
+	Hourly []usageBucket `json:"hourly"`
+	Daily  []usageBucket `json:"daily"`
+	// Heatmap is indexed by weekday (0 = Sunday) and then hour of day, UTC.
+	Heatmap [7][24]int64 `json:"heatmap"`
+}
+
+func (u *usageStats) Report(channel string) map[string]channelUsageReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	report := make(map[string]channelUsageReport)
+	for key, c := range u.channels {
+		if channel != "" && key != channel {
+			continue
+		}
+		r := channelUsageReport{Hourly: []usageBucket{}, Daily: []usageBucket{}, Heatmap: c.heatmap}
+		for hour, count := range c.hourly {
+			r.Hourly = append(r.Hourly, usageBucket{Start: time.Unix(hour, 0).UTC().Format(time.RFC3339), Count: count})
+		}
+		for day, count := range c.daily {
+			r.Daily = append(r.Daily, usageBucket{Start: day, Count: count})
+		}
+		byStart := func(a, b usageBucket) int { return strings.Compare(a.Start, b.Start) }
+		slices.SortFunc(r.Hourly, byStart)
+		slices.SortFunc(r.Daily, byStart)
+		report[key] = r
+	}
+	return report
+}
+
+var usage = newUsageStats()
+
+// recordCheckIn counts a launcher update check for a channel.
+func recordCheckIn(ch channel) {
+	requestsByChannel.Add(ch.Key(), 1)
+	usage.Record(ch.Key(), clock.Now())
+}
+
+func usageHandler(w http.ResponseWriter, r *http.Request) {
+	writeJsonResponse(w, "usage", usage.Report(r.URL.Query().Get("channel")))
+}
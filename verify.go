@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The verification sweep downloads every file of every published version
+// and checks it against its recorded checksum, so link rot in old versions
+// shows up in the versions listing instead of at download time. Versions are
+// checked as clients were given them: from the manifests kept in the
+// snapshot, the sync feed or the filesystem store, and only resolved again
+// when none is stored.
+
+type linkRotReport struct {
+	mu sync.RWMutex
+	// unavailable maps "repository:group:artifact:version" to what failed,
+	// as branches publishing the same coordinates to different repositories
+	// do not share files.
+	unavailable map[string]string
+	running     atomic.Bool
+}
+
+var linkRot = &linkRotReport{unavailable: make(map[string]string)}
+
+func (l *linkRotReport) Problem(repository, coordinates, version string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	problem, ok := l.unavailable[repository+":"+coordinates+":"+version]
+	return problem, ok
+}
+
+func (l *linkRotReport) Unavailable() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return maps.Clone(l.unavailable)
+}
+
+func channelVersions(ch channel) ([]string, error) {
+	if releaseStore != nil {
+		return releaseStore.Versions(ch)
+	}
+	items, err := listNexusVersions(ch.Repository, ch.Group, ch.Artifact)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(items))
+	for _, item := range items {
+		versions = append(versions, item.Version)
+	}
+	return versions, nil
+}
+
+// storedManifests returns the manifests kept for a channel by version, with
+// the snapshot winning over older sync feed entries.
+func storedManifests(ch channel) map[string]UpdaterResponse {
+	manifests := make(map[string]UpdaterResponse)
+	if manifestFeed != nil {
+		manifests = manifestFeed.Manifests(ch.Key())
+	}
+	if resp, ok := lastServed.Get(ch.Key()); ok {
+		manifests[resp.Version] = resp
+	}
+	return manifests
+}
+
+// verifyRelease downloads every file of a manifest and returns the first
+// problem found.
+func verifyRelease(manifest UpdaterResponse) error {
+	if err := verifyDownload(manifest.Url, manifest.Sha256); err != nil {
+		return fmt.Errorf("%s: %w", manifest.Url, err)
+	}
+	for fileName, url := range manifest.Libraries {
+		if err := verifyDownload(url, manifest.LibrarySha256[fileName]); err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+	}
+	for _, url := range manifest.Assets {
+		if err := verifyDownload(url, ""); err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+	}
+	return nil
+}
+
+// sweepReleases re-verifies all versions of every channel. Only one sweep
+// runs at a time; a version is checked once even if several channels list it.
+func sweepReleases() error {
+	if !linkRot.running.CompareAndSwap(false, true) {
+		return nil
+	}
+	defer linkRot.running.Store(false)
+	unavailable := make(map[string]string)
+	checked := make(map[string]bool)
+	var listErrors int
+	for _, ch := range allChannels() {
+		prefix := ch.Repository + ":" + ch.Group + ":" + ch.Artifact + ":"
+		stored := storedManifests(ch)
+		versions, err := channelVersions(ch)
+		if err != nil {
+			log.Printf("Warning: failed to list versions of %s for verification: %v", ch.Key(), err)
+			listErrors++
+			// Keep what the previous sweep found for this artifact.
+			for key, problem := range linkRot.Unavailable() {
+				if strings.HasPrefix(key, prefix) {
+					unavailable[key] = problem
+				}
+			}
+			continue
+		}
+		// Stored versions that are no longer listed are checked too, as
+		// launchers holding their manifests still download them.
+		for version := range stored {
+			if !slices.Contains(versions, version) {
+				versions = append(versions, version)
+			}
+		}
+		for _, version := range versions {
+			key := prefix + version
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+			manifest, ok := stored[version]
+			var err error
+			if !ok {
+				manifest, err = releaseManifest(ch, version)
+			}
+			if err == nil {
+				err = verifyRelease(manifest)
+			}
+			if err != nil {
+				log.Printf("Warning: %s %s is unavailable: %v", ch.Key(), version, err)
+				unavailable[key] = err.Error()
+			}
+		}
+	}
+	linkRot.mu.Lock()
+	linkRot.unavailable = unavailable
+	linkRot.mu.Unlock()
+	log.Printf("Verified %d versions, %d unavailable", len(checked), len(unavailable))
+	if listErrors > 0 {
+		return fmt.Errorf("Failed to list versions of %d channels", listErrors)
+	}
+	return nil
+}
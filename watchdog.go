@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"sync"
+)
+
+type WatchdogConfig struct {
+	MaxGoroutines int `json:"maxGoroutines"`
+	MaxOpenFiles  int `json:"maxOpenFiles"`
+}
+
+// resourceWatchdog samples goroutine and file descriptor counts on the
+// "watchdog" schedule. Steady growth between samples is the first sign of a
+// leak in proxying or streaming code, long before the process runs out of
+// either; crossing a threshold raises a resource alert once until the count
+// recovers.
+type resourceWatchdog struct {
+	mu       sync.Mutex
+	last     map[string]int
+	alerting map[string]bool
+}
+
+var watchdog = &resourceWatchdog{last: make(map[string]int), alerting: make(map[string]bool)}
+
+// openFileCount counts this process's file descriptors. It only works where
+// /proc is available.
+func openFileCount() (int, error) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (w *resourceWatchdog) Check(cfg WatchdogConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sample("goroutines", runtime.NumGoroutine(), cfg.MaxGoroutines)
+	if files, err := openFileCount(); err == nil {
+		w.sample("open files", files, cfg.MaxOpenFiles)
+	}
+}
+
+func (w *resourceWatchdog) sample(name string, value, max int) {
+	if prev, ok := w.last[name]; ok && prev != value {
+		log.Printf("Watchdog: %d %s (%+d)", value, name, value-prev)
+	}
+	w.last[name] = value
+	if max <= 0 {
+		return
+	}
+	over := value > max
+	if over && !w.alerting[name] {
+		reason := fmt.Sprintf("%d %s, threshold %d", value, name, max)
+		log.Printf("Warning: resource alert: %s", reason)
+		events.Publish(Event{Type: EventResourceAlert, Reason: reason})
+	} else if !over && w.alerting[name] {
+		log.Printf("Watchdog: %s back below threshold at %d", name, value)
+	}
+	w.alerting[name] = over
+}
+
+func checkResources() error {
+	watchdog.Check(config().Watchdog)
+	return nil
+}